@@ -16,80 +16,280 @@ type GridMap interface {
 	Height() int
 }
 
+// A CostMap is a GridMap with weighted terrain.  If the map given to
+// a search is a CostMap then the cost of each move is its length, 1 or
+// √2, multiplied by the cost of the cell that it enters.  A cell with
+// a cost that is not positive is treated as blocked.
+type CostMap interface {
+	GridMap
+
+	// Cost returns the cost of entering the unblocked cell
+	// at the given coordinates.
+	Cost(int, int) float64
+
+	// MinCost returns a lower bound on the cost of entering any
+	// cell, by which the heuristic is scaled.  The tighter the
+	// bound, the fewer nodes a search expands.  If it is not
+	// positive and finite then the search uses no heuristic.
+	MinCost() float64
+}
+
 // A Loc is a location in a grid.
 type Loc struct {
 	X, Y int
 }
 
-// Astar returns the shortest path and ints cost between
-// start and goal in the given grid map.
-func Astar(m GridMap, start, goal Loc) ([]Loc, float64) {
-	stride := m.Height()
-	goali := goal.X*stride + goal.Y
-	open := make(openList, 0, m.Width()*m.Height())
-	closed := makeClosedList(m)
+// Options are the options of a search.  A nil *Options
+// is the same as a pointer to the zero Options.
+type Options struct {
+	// Movement is the movement model of AstarOptions.
+	// JPSOptions always uses EightWay movement.
+	Movement Movement
 
-	starti := start.X*stride + start.Y
-	closed[starti].g = 0
-	closed[starti].f = 0
-	heap.Push(&open, &closed[starti])
+	// Stats, if non-nil, is set to the statistics
+	// of the search when it finishes.
+	Stats *SearchStats
 
-	expd, gend, begin := 0, 0, time.Now()
+	// OnExpand, if non-nil, is called on the location
+	// of each node as it is expanded.
+	OnExpand func(Loc)
+}
 
-	for len(open) > 0 {
-		n := open[0]
-		heap.Pop(&open)
-		if n.ind == goali {
-			break
-		}
+// SearchStats are the statistics of a search.
+type SearchStats struct {
+	Expanded  int           // The number of nodes expanded
+	Generated int           // The number of successors generated
+	OpenPeak  int           // The greatest length of the open list
+	Cost      float64       // The cost of the path, or -1 if there is none
+	Duration  time.Duration // The time taken by the search
+}
 
-		expd++
+// Astar returns the shortest path and ints cost between
+// start and goal in the given grid map using EightWay movement.
+// The path runs from the goal back to the cell after the start.
+// If there is no path then the path is nil and the cost is -1.
+func Astar(m GridMap, start, goal Loc) ([]Loc, float64) {
+	return AstarOptions(m, start, goal, nil)
+}
 
-		x, y := n.ind/stride, n.ind%stride
-		for _, mv := range moves {
+// AstarOptions returns the shortest path and its cost between start
+// and goal in the given grid map using the given options.  The
+// heuristic is the movement model's distance between the two cells,
+// scaled by the MinCost of the map if it is a CostMap.
+func AstarOptions(m GridMap, start, goal Loc, opts *Options) ([]Loc, float64) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	begin := time.Now()
+	s := astar(m, opts.Movement, start, goal, opts.OnExpand)
+	return s.finish(opts, begin)
+}
+
+// astar returns the completed A* search from start to goal.
+func astar(m GridMap, model Movement, start, goal Loc, onExpand func(Loc)) *search {
+	m, costs, scale := costGrid(m)
+	h := func(x, y int) float64 {
+		return model.dist(x, y, goal.X, goal.Y) * scale
+	}
+	s := newSearch(m, start, goal, h, onExpand)
+	s.run(func(n *node, x, y int) {
+		for _, mv := range model.moves(y) {
 			if !mv.ok(m, x, y) {
 				continue
 			}
-			gend++
 			kidx, kidy := x+mv.dx, y+mv.dy
-			kidi := kidx*stride + kidy
-			kid := &closed[kidi]
-			cost := n.g + mv.cost
-			if kid.g >= 0 && kid.g < cost {
-				continue
+			cost := mv.cost
+			if costs != nil {
+				cost *= costs.Cost(kidx, kidy)
 			}
-			kid.parent = n.ind
-			kid.g = cost
-			if kid.h < 0 {
-				kid.h = octiledist(kidx, kidy, goal.X, goal.Y)
-			}
-			kid.f = kid.g + kid.h
-			if kid.pqindex >= 0 {
-				heap.Remove(&open, kid.pqindex)
-			}
-			heap.Push(&open, kid)
+			s.generate(n, kidx, kidy, n.g+cost)
 		}
+	})
+	return s
+}
+
+// A search is the state of an A* search.  The successors of
+// a node are generated by a function given to run, so the
+// same search can be used with different successor functions.
+type search struct {
+	m          GridMap
+	stride     int
+	goali      int
+	open       openList
+	closed     []node
+	h          func(x, y int) float64
+	onExpand   func(Loc)
+	expd, gend int
+	peak       int // The greatest length of the open list
+}
+
+// newSearch returns a new search from start to goal using the
+// given heuristic.  If onExpand is non-nil then it is called on
+// the location of each node as it is expanded.
+func newSearch(m GridMap, start, goal Loc, h func(x, y int) float64, onExpand func(Loc)) *search {
+	s := &search{
+		m:        m,
+		stride:   m.Height(),
+		open:     make(openList, 0, m.Width()*m.Height()),
+		closed:   makeClosedList(m),
+		h:        h,
+		onExpand: onExpand,
+		peak:     1,
 	}
+	s.goali = goal.X*s.stride + goal.Y
+	starti := start.X*s.stride + start.Y
+	s.closed[starti].g = 0
+	s.closed[starti].f = 0
+	heap.Push(&s.open, &s.closed[starti])
+	return s
+}
+
+// run runs the search until the goal is expanded or the open
+// list is empty, calling expand to generate the successors of
+// each node, with its x and y coordinates.
+func (s *search) run(expand func(n *node, x, y int)) {
+	for len(s.open) > 0 {
+		n := s.open[0]
+		heap.Pop(&s.open)
+		if n.ind == s.goali {
+			break
+		}
 
-	fmt.Println("path cost", closed[goali].g)
-	fmt.Println("expanded", expd)
-	fmt.Println("generated", gend)
-	fmt.Println("seconds", time.Since(begin))
+		s.expd++
 
-	return makePath(m, closed, goali), closed[goali].g
+		x, y := n.ind/s.stride, n.ind%s.stride
+		if s.onExpand != nil {
+			s.onExpand(Loc{x, y})
+		}
+		expand(n, x, y)
+	}
+}
+
+// generate generates the successor of n at x, y, reached with
+// cost g, adding it to the open list if it is new or if g is
+// less than its previous cost.
+func (s *search) generate(n *node, x, y int, g float64) {
+	s.gend++
+	kid := &s.closed[x*s.stride+y]
+	if kid.g >= 0 && kid.g <= g {
+		return
+	}
+	kid.parent = n.ind
+	kid.g = g
+	if kid.h < 0 {
+		kid.h = s.h(x, y)
+	}
+	kid.f = kid.g + kid.h
+	if kid.pqindex >= 0 {
+		heap.Remove(&s.open, kid.pqindex)
+	}
+	heap.Push(&s.open, kid)
+	s.peak = max(s.peak, len(s.open))
+}
+
+// finish returns the path found by the search and its cost,
+// setting opts.Stats if it is non-nil.  The search began at
+// the given time.
+func (s *search) finish(opts *Options, begin time.Time) ([]Loc, float64) {
+	path, g := s.path(), s.closed[s.goali].g
+	if opts.Stats != nil {
+		*opts.Stats = SearchStats{
+			Expanded:  s.expd,
+			Generated: s.gend,
+			OpenPeak:  s.peak,
+			Cost:      g,
+			Duration:  time.Since(begin),
+		}
+	}
+	return path, g
+}
+
+// path returns the path found by the search.
+func (s *search) path() []Loc {
+	return makePath(s.m, s.closed, s.goali)
+}
+
+// costGrid returns the map as it is searched.  If the map is a CostMap
+// then it is returned as a CostMap in which the cells with a cost that
+// is not positive are blocked, along with its heuristic scale.
+// Otherwise, the map is returned unchanged with a nil CostMap and
+// a scale of 1.
+func costGrid(m GridMap) (GridMap, CostMap, float64) {
+	costs, ok := m.(CostMap)
+	if !ok {
+		return m, nil, 1
+	}
+	scale := costs.MinCost()
+	if !(scale > 0) || math.IsInf(scale, 1) {
+		scale = 0
+	}
+	costs = positiveCosts{costs}
+	return costs, costs, scale
+}
+
+// positiveCosts is a CostMap in which the cells with
+// a cost that is not positive are blocked.
+type positiveCosts struct {
+	CostMap
+}
+
+func (m positiveCosts) Blocked(x, y int) bool {
+	return m.CostMap.Blocked(x, y) || !(m.CostMap.Cost(x, y) > 0)
+}
+
+// A Movement is a model of the moves that are allowed
+// between the cells of a grid.
+type Movement int
+
+const (
+	// EightWay allows moves to the 8 neighbors of a cell.
+	// Straight moves cost 1 and diagonal moves cost √2.  A
+	// diagonal move may not cut the corner of a blocked cell.
+	EightWay Movement = iota
+
+	// FourWay allows moves to the 4 orthogonal
+	// neighbors of a cell, each costing 1.
+	FourWay
+
+	// Hex treats the grid as a map of hexagons with horizontal
+	// rows, in which each odd row (odd y) is shifted half of a
+	// cell to the right (+x).  It allows moves to the 6
+	// neighbors of a cell, each costing 1.
+	Hex
+)
+
+// moves returns the moves from a cell in row y.
+func (model Movement) moves(y int) []move {
+	switch model {
+	case EightWay:
+		return moves[:]
+	case FourWay:
+		return moves[:4]
+	case Hex:
+		return hexMoves[y&1][:]
+	}
+	panic(fmt.Sprintf("gridpath: unknown Movement %d", int(model)))
+}
+
+// dist returns the heuristic cost-to-go estimate between x0,y0
+// and x1,y1: the cost of the shortest path between them if no
+// cells are blocked and every cell costs 1.
+func (model Movement) dist(x0, y0, x1, y1 int) float64 {
+	switch model {
+	case EightWay:
+		return octiledist(x0, y0, x1, y1)
+	case FourWay:
+		return float64(abs(x0-x1) + abs(y0-y1))
+	case Hex:
+		return hexdist(x0, y0, x1, y1)
+	}
+	panic(fmt.Sprintf("gridpath: unknown Movement %d", int(model)))
 }
 
 // octiledist returns the 8-way heuristic cost-to-go estimate
 // between x0,y0 and x1,y1.
 func octiledist(x0, y0, x1, y1 int) float64 {
-	dx := x0 - x1
-	if dx < 0 {
-		dx = -dx
-	}
-	dy := y0 - y1
-	if dy < 0 {
-		dy = -dy
-	}
+	dx, dy := abs(x0-x1), abs(y0-y1)
 	diag, straight := dx, dy
 	if straight < diag {
 		diag, straight = straight, diag
@@ -97,6 +297,35 @@ func octiledist(x0, y0, x1, y1 int) float64 {
 	return float64(straight-diag) + float64(diag)*math.Sqrt(2)
 }
 
+// hexdist returns the number of moves between the hexagons
+// x0,y0 and x1,y1.  The hexagons are converted to axial
+// coordinates, in which the column is shifted left by half
+// of the row, so that every neighbor is one of six fixed
+// offsets.
+func hexdist(x0, y0, x1, y1 int) float64 {
+	q0 := x0 - (y0-y0&1)/2
+	q1 := x1 - (y1-y1&1)/2
+	dq, dr := q0-q1, y0-y1
+	return float64(abs(dq)+abs(dr)+abs(dq+dr)) / 2
+}
+
+func sign(x int) int {
+	switch {
+	case x < 0:
+		return -1
+	case x > 0:
+		return 1
+	}
+	return 0
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
 // A move is a legal move within the grid specified
 // by the delta from the current location and the
 // cost.
@@ -107,7 +336,8 @@ type move struct {
 }
 
 var (
-	// moves is the array of all valid moves.
+	// moves is the array of all valid EightWay moves.  The
+	// first four are the FourWay moves.
 	moves = [...]move{
 		{1, 0, []Loc{}, 1},
 		{-1, 0, []Loc{}, 1},
@@ -118,6 +348,27 @@ var (
 		{-1, 1, []Loc{{-1, 0}, {0, 1}}, math.Sqrt(2)},
 		{-1, -1, []Loc{{-1, 0}, {0, -1}}, math.Sqrt(2)},
 	}
+
+	// hexMoves is the array of valid Hex moves from
+	// cells in even rows and in odd rows.
+	hexMoves = [2][6]move{
+		{
+			{1, 0, []Loc{}, 1},
+			{-1, 0, []Loc{}, 1},
+			{-1, -1, []Loc{}, 1},
+			{0, -1, []Loc{}, 1},
+			{-1, 1, []Loc{}, 1},
+			{0, 1, []Loc{}, 1},
+		},
+		{
+			{1, 0, []Loc{}, 1},
+			{-1, 0, []Loc{}, 1},
+			{0, -1, []Loc{}, 1},
+			{1, -1, []Loc{}, 1},
+			{0, 1, []Loc{}, 1},
+			{1, 1, []Loc{}, 1},
+		},
+	}
 )
 
 // ok returns true if the given move is allowed in the grid.
@@ -138,7 +389,9 @@ func clear(m GridMap, x, y int) bool {
 }
 
 // makePath returns the path from the goal to the first node
-// without any parent index (the start node).
+// without any parent index (the start node).  A parent needn't
+// be adjacent to its node, but they must be joined by a straight
+// or diagonal line, every cell of which is on the path.
 func makePath(m GridMap, nodes []node, goali int) (path []Loc) {
 	if nodes[goali].parent < 0 {
 		return
@@ -148,7 +401,11 @@ func makePath(m GridMap, nodes []node, goali int) (path []Loc) {
 	stride := m.Height()
 	for i := goali; nodes[i].parent >= 0; i = nodes[i].parent {
 		x, y := i/stride, i%stride
-		rev = append(rev, Loc{x, y})
+		px, py := nodes[i].parent/stride, nodes[i].parent%stride
+		dx, dy := sign(px-x), sign(py-y)
+		for ; x != px || y != py; x, y = x+dx, y+dy {
+			rev = append(rev, Loc{x, y})
+		}
 	}
 	for _, loc := range rev {
 		path = append(path, loc)
@@ -216,6 +473,7 @@ func makeClosedList(m GridMap) []node {
 		closed[i].ind = i
 		closed[i].parent = -1
 		closed[i].g = -1
+		closed[i].h = -1
 		closed[i].pqindex = -1
 	}
 	return closed
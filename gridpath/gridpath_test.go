@@ -3,6 +3,8 @@ package gridpath
 import (
 	"bufio"
 	"fmt"
+	"math"
+	"math/rand"
 	"os"
 	"testing"
 )
@@ -94,3 +96,281 @@ func readGridMap(in *bufio.Reader) (m gridmap, err error) {
 	_, err = fmt.Fscanf(in, "%d %d %d %d", &m.start.X, &m.start.Y, &m.goal.X, &m.goal.Y)
 	return
 }
+
+// A costmap is a gridmap with weighted terrain.
+type costmap struct {
+	gridmap
+	cost []float64
+}
+
+func (m costmap) Cost(x, y int) float64 {
+	return m.cost[x*m.h+y]
+}
+
+// MinCost returns 1, the least cost given by randomMap.
+func (m costmap) MinCost() float64 {
+	return 1
+}
+
+// randomMap returns a random w×h map in which each cell is
+// blocked with probability p.  If weighted is true then the
+// cells have random costs between 1 and 5.
+func randomMap(r *rand.Rand, w, h int, p float64, weighted bool) GridMap {
+	m := gridmap{w: w, h: h, blkd: make([]bool, w*h)}
+	for i := range m.blkd {
+		m.blkd[i] = r.Float64() < p
+	}
+	if !weighted {
+		return m
+	}
+	c := costmap{gridmap: m, cost: make([]float64, w*h)}
+	for i := range c.cost {
+		c.cost[i] = float64(1 + r.Intn(5))
+	}
+	return c
+}
+
+// neighbors returns the neighbors of x, y under the movement
+// model and the length of the move to each.  It is written
+// independently of the moves tables.
+func neighbors(m GridMap, model Movement, x, y int) (locs []Loc, lens []float64) {
+	free := func(x, y int) bool {
+		return x >= 0 && x < m.Width() && y >= 0 && y < m.Height() && !m.Blocked(x, y)
+	}
+	add := func(dx, dy int, l float64) {
+		if free(x+dx, y+dy) {
+			locs = append(locs, Loc{x + dx, y + dy})
+			lens = append(lens, l)
+		}
+	}
+	add(1, 0, 1)
+	add(-1, 0, 1)
+	switch model {
+	case FourWay:
+		add(0, 1, 1)
+		add(0, -1, 1)
+	case EightWay:
+		add(0, 1, 1)
+		add(0, -1, 1)
+		for _, d := range []Loc{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}} {
+			if free(x+d.X, y) && free(x, y+d.Y) {
+				add(d.X, d.Y, math.Sqrt(2))
+			}
+		}
+	case Hex:
+		// Odd rows are shifted right, so the cells above
+		// and below are either x-1 and x or x and x+1.
+		left := -1 + y&1
+		for _, dy := range []int{-1, 1} {
+			add(left, dy, 1)
+			add(left+1, dy, 1)
+		}
+	}
+	return
+}
+
+// dijkstra returns the cost of the shortest path from start
+// to goal, or -1 if there is no path.
+func dijkstra(m GridMap, model Movement, start, goal Loc) float64 {
+	dist := map[Loc]float64{start: 0}
+	done := map[Loc]bool{}
+	for {
+		u, best := Loc{}, math.Inf(1)
+		for l, d := range dist {
+			if !done[l] && d < best {
+				u, best = l, d
+			}
+		}
+		if math.IsInf(best, 1) {
+			return -1
+		}
+		if u == goal {
+			return best
+		}
+		done[u] = true
+		locs, lens := neighbors(m, model, u.X, u.Y)
+		for i, v := range locs {
+			c := lens[i]
+			if cm, ok := m.(CostMap); ok {
+				c *= cm.Cost(v.X, v.Y)
+			}
+			if d, ok := dist[v]; !ok || best+c < d {
+				dist[v] = best + c
+			}
+		}
+	}
+}
+
+// checkPath fails the test if the path, which runs from the
+// goal back to the cell after start, is not made of legal
+// moves or does not have the given cost.
+func checkPath(t *testing.T, m GridMap, model Movement, start, goal Loc, path []Loc, cost float64) {
+	if start == goal {
+		return
+	}
+	if len(path) == 0 || path[0] != goal {
+		t.Errorf("path %v does not begin at the goal %v", path, goal)
+		return
+	}
+	sum := 0.0
+	for i := len(path) - 1; i >= 0; i-- {
+		prev := start
+		if i < len(path)-1 {
+			prev = path[i+1]
+		}
+		locs, lens := neighbors(m, model, prev.X, prev.Y)
+		j := 0
+		for j < len(locs) && locs[j] != path[i] {
+			j++
+		}
+		if j == len(locs) {
+			t.Errorf("illegal move from %v to %v", prev, path[i])
+			return
+		}
+		c := lens[j]
+		if cm, ok := m.(CostMap); ok {
+			c *= cm.Cost(path[i].X, path[i].Y)
+		}
+		sum += c
+	}
+	if math.Abs(sum-cost) > 1e-9 {
+		t.Errorf("path costs %g, but the search returned %g", sum, cost)
+	}
+}
+
+func TestMovement(t *testing.T) {
+	r := rand.New(rand.NewSource(0))
+	for _, model := range []Movement{EightWay, FourWay, Hex} {
+		for _, weighted := range []bool{false, true} {
+			for n := 0; n < 50; n++ {
+				m := randomMap(r, 12, 10, 0.25, weighted)
+				start := Loc{r.Intn(12), r.Intn(10)}
+				goal := Loc{r.Intn(12), r.Intn(10)}
+				if m.Blocked(start.X, start.Y) || m.Blocked(goal.X, goal.Y) {
+					continue
+				}
+				want := dijkstra(m, model, start, goal)
+				if want < 0 {
+					continue
+				}
+				path, cost := AstarOptions(m, start, goal, &Options{Movement: model})
+				if math.Abs(cost-want) > 1e-9 {
+					t.Errorf("model %d, weighted=%t, %v to %v: cost %g, expected %g",
+						model, weighted, start, goal, cost, want)
+				}
+				checkPath(t, m, model, start, goal, path, cost)
+			}
+		}
+	}
+}
+
+// A minCostMap is a costmap with a given MinCost.
+type minCostMap struct {
+	costmap
+	min float64
+}
+
+func (m minCostMap) MinCost() float64 {
+	return m.min
+}
+
+// TestCostMapBadCosts tests that cells with costs that are not
+// positive are blocked, and that a MinCost that is not positive
+// and finite disables the heuristic rather than breaking the search.
+func TestCostMapBadCosts(t *testing.T) {
+	r := rand.New(rand.NewSource(0))
+	for _, bad := range []float64{0, -1, math.NaN()} {
+		// A wall of bad cells across the middle of an open map.
+		m := randomMap(r, 5, 5, 0, true).(costmap)
+		for y := 0; y < 5; y++ {
+			m.cost[2*5+y] = bad
+		}
+		if path, cost := Astar(m, Loc{0, 2}, Loc{4, 2}); path != nil || cost != -1 {
+			t.Errorf("cost %g: Astar found path %v, cost %g through the wall", bad, path, cost)
+		}
+		p := NewPlanner(m, Loc{0, 2}, Loc{4, 2})
+		if path, cost := p.Path(); path != nil || cost != -1 {
+			t.Errorf("cost %g: Planner found path %v, cost %g through the wall", bad, path, cost)
+		}
+	}
+
+	for _, min := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+		for n := 0; n < 20; n++ {
+			m := minCostMap{randomMap(r, 12, 10, 0.25, true).(costmap), min}
+			start, goal := Loc{r.Intn(12), r.Intn(10)}, Loc{r.Intn(12), r.Intn(10)}
+			if m.Blocked(start.X, start.Y) || m.Blocked(goal.X, goal.Y) {
+				continue
+			}
+			want := dijkstra(m, EightWay, start, goal)
+			if _, cost := Astar(m, start, goal); math.Abs(cost-want) > 1e-9 {
+				t.Errorf("MinCost %g: %v to %v: cost %g, expected %g", min, start, goal, cost, want)
+			}
+		}
+	}
+}
+
+func TestMovementDist(t *testing.T) {
+	// On an open map, the heuristic must be exact.
+	r := rand.New(rand.NewSource(1))
+	m := randomMap(r, 9, 9, 0, false)
+	for _, model := range []Movement{EightWay, FourWay, Hex} {
+		for x := 0; x < 9; x++ {
+			for y := 0; y < 9; y++ {
+				want := dijkstra(m, model, Loc{4, 3}, Loc{x, y})
+				if d := model.dist(4, 3, x, y); math.Abs(d-want) > 1e-9 {
+					t.Errorf("model %d: dist to %d, %d is %g, expected %g", model, x, y, d, want)
+				}
+			}
+		}
+	}
+}
+
+func TestSearchStats(t *testing.T) {
+	m, err := loadGridMap("1.map")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, search := range []struct {
+		name string
+		f    func(GridMap, Loc, Loc, *Options) ([]Loc, float64)
+	}{{"Astar", AstarOptions}, {"JPS", JPSOptions}} {
+		var stats SearchStats
+		var expanded []Loc
+		opts := Options{
+			Stats:    &stats,
+			OnExpand: func(l Loc) { expanded = append(expanded, l) },
+		}
+		_, cost := search.f(m, m.start, m.goal, &opts)
+		if stats.Cost != cost {
+			t.Errorf("%s: Stats.Cost=%g, expected %g", search.name, stats.Cost, cost)
+		}
+		if stats.Expanded == 0 || stats.Expanded != len(expanded) {
+			t.Errorf("%s: Stats.Expanded=%d, OnExpand was called %d times",
+				search.name, stats.Expanded, len(expanded))
+		}
+		if len(expanded) > 0 && expanded[0] != m.start {
+			t.Errorf("%s: expanded %v first, expected the start %v", search.name, expanded[0], m.start)
+		}
+		if stats.Generated < stats.Expanded {
+			t.Errorf("%s: Stats.Generated=%d is less than Stats.Expanded=%d",
+				search.name, stats.Generated, stats.Expanded)
+		}
+		if stats.OpenPeak < 1 || stats.OpenPeak > stats.Generated+1 {
+			t.Errorf("%s: Stats.OpenPeak=%d with %d generated", search.name, stats.OpenPeak, stats.Generated)
+		}
+		if stats.Duration < 0 {
+			t.Errorf("%s: Stats.Duration=%s", search.name, stats.Duration)
+		}
+	}
+
+	// A search with no path reports a cost of -1.
+	m.blkd[m.goal.X*m.h+m.goal.Y-1] = true
+	m.blkd[(m.goal.X-1)*m.h+m.goal.Y] = true
+	m.blkd[(m.goal.X+1)*m.h+m.goal.Y] = true
+	m.blkd[m.goal.X*m.h+m.goal.Y+1] = true
+	var stats SearchStats
+	path, cost := AstarOptions(m, m.start, m.goal, &Options{Stats: &stats})
+	if path != nil || cost != -1 || stats.Cost != -1 {
+		t.Errorf("no path: got path %v, cost %g, Stats.Cost %g", path, cost, stats.Cost)
+	}
+}
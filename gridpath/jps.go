@@ -0,0 +1,179 @@
+package gridpath
+
+import "time"
+
+// JPS returns the shortest path and its cost between start and goal
+// in the given grid map using Jump Point Search (Harabor and Grastien,
+// 2011).  The path and its cost are the same as those found by Astar,
+// but JPS only expands the jump points along the way: the cells at
+// which an optimal path may need to turn.  On large open maps this is
+// a small fraction of the cells expanded by Astar.
+//
+// JPS uses EightWay movement, in which diagonal moves may not cut the
+// corner of a blocked cell, and every cell costs 1; the costs of a
+// CostMap are ignored.
+func JPS(m GridMap, start, goal Loc) ([]Loc, float64) {
+	return JPSOptions(m, start, goal, nil)
+}
+
+// JPSOptions returns the shortest path and its cost between start and
+// goal in the given grid map using Jump Point Search with the given
+// options.  The Movement option is ignored.  Only the jump points are
+// expanded, generated and passed to OnExpand.
+func JPSOptions(m GridMap, start, goal Loc, opts *Options) ([]Loc, float64) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	begin := time.Now()
+	s := jps(m, start, goal, opts.OnExpand)
+	return s.finish(opts, begin)
+}
+
+// jps returns the completed Jump Point Search from start to goal.
+// The parent of each node is the previous jump point, which is
+// joined to it by a straight or diagonal line.
+func jps(m GridMap, start, goal Loc, onExpand func(Loc)) *search {
+	h := func(x, y int) float64 {
+		return octiledist(x, y, goal.X, goal.Y)
+	}
+	s := newSearch(m, start, goal, h, onExpand)
+	j := jumper{m: m, goal: goal}
+	s.run(func(n *node, x, y int) {
+		for _, d := range j.directions(s, n, x, y) {
+			if jx, jy, ok := j.jump(x, y, d.X, d.Y); ok {
+				s.generate(n, jx, jy, n.g+octiledist(x, y, jx, jy))
+			}
+		}
+	})
+	return s
+}
+
+// A jumper finds the jump points of a map.
+type jumper struct {
+	m    GridMap
+	goal Loc
+
+	// dirs is reused to hold the directions
+	// returned by the directions method.
+	dirs []Loc
+}
+
+// directions returns the directions in which to jump from the node n
+// at x, y.  From the start node, every legal move is a direction.
+// Otherwise, the directions that can only lead to cells that are
+// reached at least as cheaply through the node's parent, without
+// passing through n, are pruned.
+func (j *jumper) directions(s *search, n *node, x, y int) []Loc {
+	j.dirs = j.dirs[:0]
+	if n.parent < 0 {
+		for _, mv := range moves {
+			if mv.ok(j.m, x, y) {
+				j.dirs = append(j.dirs, Loc{mv.dx, mv.dy})
+			}
+		}
+		return j.dirs
+	}
+
+	px, py := n.parent/s.stride, n.parent%s.stride
+	dx, dy := sign(x-px), sign(y-py)
+	switch {
+	case dx != 0 && dy != 0:
+		vert, horiz := clear(j.m, x, y+dy), clear(j.m, x+dx, y)
+		if vert {
+			j.dirs = append(j.dirs, Loc{0, dy})
+		}
+		if horiz {
+			j.dirs = append(j.dirs, Loc{dx, 0})
+		}
+		if vert && horiz {
+			j.dirs = append(j.dirs, Loc{dx, dy})
+		}
+
+	case dx != 0:
+		next := clear(j.m, x+dx, y)
+		up, down := clear(j.m, x, y+1), clear(j.m, x, y-1)
+		if next {
+			j.dirs = append(j.dirs, Loc{dx, 0})
+		}
+		if up {
+			j.dirs = append(j.dirs, Loc{0, 1})
+			if next {
+				j.dirs = append(j.dirs, Loc{dx, 1})
+			}
+		}
+		if down {
+			j.dirs = append(j.dirs, Loc{0, -1})
+			if next {
+				j.dirs = append(j.dirs, Loc{dx, -1})
+			}
+		}
+
+	default:
+		next := clear(j.m, x, y+dy)
+		right, left := clear(j.m, x+1, y), clear(j.m, x-1, y)
+		if next {
+			j.dirs = append(j.dirs, Loc{0, dy})
+		}
+		if right {
+			j.dirs = append(j.dirs, Loc{1, 0})
+			if next {
+				j.dirs = append(j.dirs, Loc{1, dy})
+			}
+		}
+		if left {
+			j.dirs = append(j.dirs, Loc{-1, 0})
+			if next {
+				j.dirs = append(j.dirs, Loc{-1, dy})
+			}
+		}
+	}
+	return j.dirs
+}
+
+// jump returns the first jump point reached by moving from x, y in
+// the direction dx, dy, and true, or false if a blocked cell is
+// reached first.  The first move must be legal.
+//
+// A cell reached by a straight move is a jump point if it is the
+// goal, or if one of its side neighbors is clear but the cell behind
+// that neighbor is blocked: the neighbor can't be reached diagonally
+// without cutting a corner, so an optimal path may turn there.  A
+// cell reached by a diagonal move is a jump point if it is the goal
+// or if a straight jump from it finds a jump point.
+func (j *jumper) jump(x, y, dx, dy int) (int, int, bool) {
+	m := j.m
+	for {
+		x, y = x+dx, y+dy
+		if !clear(m, x, y) {
+			return 0, 0, false
+		}
+		if x == j.goal.X && y == j.goal.Y {
+			return x, y, true
+		}
+		switch {
+		case dx != 0 && dy != 0:
+			if _, _, ok := j.jump(x, y, dx, 0); ok {
+				return x, y, true
+			}
+			if _, _, ok := j.jump(x, y, 0, dy); ok {
+				return x, y, true
+			}
+			// The next diagonal move may not cut a corner.
+			if !clear(m, x+dx, y) || !clear(m, x, y+dy) {
+				return 0, 0, false
+			}
+
+		case dx != 0:
+			if clear(m, x, y+1) && !clear(m, x-dx, y+1) ||
+				clear(m, x, y-1) && !clear(m, x-dx, y-1) {
+				return x, y, true
+			}
+
+		default:
+			if clear(m, x+1, y) && !clear(m, x+1, y-dy) ||
+				clear(m, x-1, y) && !clear(m, x-1, y-dy) {
+				return x, y, true
+			}
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package gridpath
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestJPSMap(t *testing.T) {
+	m, err := loadGridMap("1.map")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expectedCost = 15.071067811865476
+	path, cost := JPS(m, m.start, m.goal)
+	if math.Abs(cost-expectedCost) > 1e-9 {
+		t.Errorf("Expected cost %g, got %g", expectedCost, cost)
+	}
+	checkPath(t, m, EightWay, m.start, m.goal, path, cost)
+}
+
+func TestJPS(t *testing.T) {
+	r := rand.New(rand.NewSource(0))
+	for _, p := range []float64{0, 0.1, 0.25, 0.4} {
+		for n := 0; n < 100; n++ {
+			m := randomMap(r, 32, 24, p, false)
+			start := Loc{r.Intn(32), r.Intn(24)}
+			goal := Loc{r.Intn(32), r.Intn(24)}
+			if m.Blocked(start.X, start.Y) || m.Blocked(goal.X, goal.Y) {
+				continue
+			}
+			a := astar(m, EightWay, start, goal, nil)
+			j := jps(m, start, goal, nil)
+			want, got := a.closed[a.goali].g, j.closed[j.goali].g
+			if math.Abs(got-want) > 1e-9 {
+				t.Errorf("p=%g, %v to %v: cost %g, expected %g", p, start, goal, got, want)
+				continue
+			}
+			if want < 0 {
+				continue
+			}
+			checkPath(t, m, EightWay, start, goal, j.path(), got)
+			if j.expd > a.expd {
+				t.Errorf("p=%g, %v to %v: JPS expanded %d, Astar expanded %d",
+					p, start, goal, j.expd, a.expd)
+			}
+		}
+	}
+}
+
+// benchMap returns a random 256×256 map in which each cell
+// is blocked with probability p, with a path between its
+// opposite corners.
+func benchMap(p float64) (GridMap, Loc, Loc) {
+	r := rand.New(rand.NewSource(0))
+	start, goal := Loc{0, 0}, Loc{255, 255}
+	for {
+		m := randomMap(r, 256, 256, p, false).(gridmap)
+		m.blkd[0] = false
+		m.blkd[len(m.blkd)-1] = false
+		if s := jps(m, start, goal, nil); s.closed[s.goali].g >= 0 {
+			return m, start, goal
+		}
+	}
+}
+
+// benchSearch benchmarks a search on open and cluttered
+// maps, reporting the nodes that it expands and generates.
+func benchSearch(b *testing.B, run func(GridMap, Loc, Loc) *search) {
+	for _, bench := range []struct {
+		name string
+		p    float64
+	}{{"open", 0}, {"random10", 0.1}, {"random20", 0.2}} {
+		b.Run(bench.name, func(b *testing.B) {
+			m, start, goal := benchMap(bench.p)
+			b.ResetTimer()
+			var s *search
+			for i := 0; i < b.N; i++ {
+				s = run(m, start, goal)
+			}
+			b.ReportMetric(float64(s.expd), "expanded/op")
+			b.ReportMetric(float64(s.gend), "generated/op")
+		})
+	}
+}
+
+func BenchmarkAstar(b *testing.B) {
+	benchSearch(b, func(m GridMap, start, goal Loc) *search {
+		return astar(m, EightWay, start, goal, nil)
+	})
+}
+
+func BenchmarkJPS(b *testing.B) {
+	benchSearch(b, func(m GridMap, start, goal Loc) *search {
+		return jps(m, start, goal, nil)
+	})
+}
@@ -0,0 +1,250 @@
+// Package mapfile reads and writes the ASCII grid map (.map) and
+// scenario (.scen) formats of the grid pathfinding benchmarks
+// (Sturtevant, "Benchmarks for Grid-Based Pathfinding", 2012).
+//
+// A map file is a header followed by the rows of the map:
+//
+//	type octile
+//	height 4
+//	width 6
+//	map
+//	@@@@@@
+//	@..T.@
+//	@....@
+//	@@@@@@
+//
+// The cells '.' and 'G' are passable terrain, 'S' is swamp,
+// which is also passable, and all others, such as '@' and 'O'
+// (out of bounds), 'T' (trees) and 'W' (water), are blocked.
+//
+// A scenario file is a version line followed by one line per
+// problem with tab-separated fields:
+//
+//	version 1
+//	bucket map width height startx starty goalx goaly optimal
+//
+// The optimal cost is that of EightWay movement, in which diagonal
+// moves cost √2 and may not cut the corner of a blocked cell.
+package mapfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"code.google.com/p/eaburns/gridpath"
+)
+
+// A Map is a grid map read from a map file.
+// It implements the gridpath.GridMap interface.
+type Map struct {
+	// Type is the type of the map, usually "octile".
+	Type string
+
+	// W and H are the width and height of the map.
+	W, H int
+
+	// Cells are the cells of the map, row by row.
+	Cells []byte
+}
+
+// Width returns the width of the map.
+func (m *Map) Width() int {
+	return m.W
+}
+
+// Height returns the height of the map.
+func (m *Map) Height() int {
+	return m.H
+}
+
+// Blocked returns true if the cell at x, y is not passable.
+func (m *Map) Blocked(x, y int) bool {
+	switch m.Cells[y*m.W+x] {
+	case '.', 'G', 'S':
+		return false
+	}
+	return true
+}
+
+// ReadMap reads a map in the map file format.
+func ReadMap(r io.Reader) (*Map, error) {
+	in := bufio.NewScanner(r)
+	line := 0
+	next := func() (string, bool) {
+		if !in.Scan() {
+			return "", false
+		}
+		line++
+		return strings.TrimRight(in.Text(), "\r"), true
+	}
+
+	m := &Map{W: -1, H: -1}
+	for {
+		l, ok := next()
+		if !ok {
+			return nil, readErr(in, line, "missing map line")
+		}
+		f := strings.Fields(l)
+		if len(f) == 1 && f[0] == "map" {
+			break
+		}
+		if len(f) != 2 {
+			return nil, fmt.Errorf("mapfile: line %d: malformed header %q", line, l)
+		}
+		var err error
+		switch f[0] {
+		case "type":
+			m.Type = f[1]
+		case "height":
+			m.H, err = strconv.Atoi(f[1])
+		case "width":
+			m.W, err = strconv.Atoi(f[1])
+		default:
+			return nil, fmt.Errorf("mapfile: line %d: unknown header %q", line, f[0])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("mapfile: line %d: %s", line, err)
+		}
+	}
+	if m.W < 0 || m.H < 0 {
+		return nil, fmt.Errorf("mapfile: line %d: missing width or height", line)
+	}
+
+	for y := 0; y < m.H; y++ {
+		l, ok := next()
+		if !ok {
+			return nil, readErr(in, line, fmt.Sprintf("missing row %d", y))
+		}
+		if len(l) != m.W {
+			return nil, fmt.Errorf("mapfile: line %d: row %d has width %d, expected %d", line, y, len(l), m.W)
+		}
+		m.Cells = append(m.Cells, l...)
+	}
+	return m, in.Err()
+}
+
+// WriteMap writes a grid map in the map file format.  If the map
+// is a *Map then its cells are written as they are, and its Type
+// is written as "octile" if it is empty; otherwise blocked cells
+// are written as '@' and passable cells as '.'.
+func WriteMap(w io.Writer, gm gridpath.GridMap) error {
+	m, ok := gm.(*Map)
+	if !ok {
+		m = &Map{Type: "octile", W: gm.Width(), H: gm.Height()}
+		m.Cells = make([]byte, m.W*m.H)
+		for y := 0; y < m.H; y++ {
+			for x := 0; x < m.W; x++ {
+				m.Cells[y*m.W+x] = '.'
+				if gm.Blocked(x, y) {
+					m.Cells[y*m.W+x] = '@'
+				}
+			}
+		}
+	}
+	if m.W < 0 || m.H < 0 || len(m.Cells) != m.W*m.H {
+		return fmt.Errorf("mapfile: %d cells in a %d×%d map", len(m.Cells), m.W, m.H)
+	}
+	typ := m.Type
+	if typ == "" {
+		typ = "octile"
+	}
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "type %s\nheight %d\nwidth %d\nmap\n", typ, m.H, m.W)
+	for y := 0; y < m.H; y++ {
+		out.Write(m.Cells[y*m.W : (y+1)*m.W])
+		out.WriteByte('\n')
+	}
+	return out.Flush()
+}
+
+// A Scenario is a pathfinding problem from a scenario file.
+type Scenario struct {
+	// Bucket groups problems of similar length.
+	Bucket int
+
+	// Map is the name of the map file of the problem.
+	Map string
+
+	// Width and Height are the dimensions of the map.
+	Width, Height int
+
+	// Start and Goal are the locations of the problem.
+	Start, Goal gridpath.Loc
+
+	// Optimal is the cost of an optimal path.
+	Optimal float64
+}
+
+// ReadScen reads the problems of a scenario file.
+func ReadScen(r io.Reader) ([]Scenario, error) {
+	in := bufio.NewScanner(r)
+	var scens []Scenario
+	for line := 1; in.Scan(); line++ {
+		l := strings.TrimRight(in.Text(), "\r")
+		if line == 1 && strings.HasPrefix(l, "version") {
+			if v := strings.TrimSpace(l[len("version"):]); v != "1" && v != "1.0" {
+				return nil, fmt.Errorf("mapfile: line %d: unknown version %q", line, v)
+			}
+			continue
+		}
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		s, err := parseScenario(l)
+		if err != nil {
+			return nil, fmt.Errorf("mapfile: line %d: %s", line, err)
+		}
+		scens = append(scens, s)
+	}
+	return scens, in.Err()
+}
+
+// parseScenario parses a problem line of a scenario file.
+func parseScenario(l string) (Scenario, error) {
+	var s Scenario
+	f := strings.Split(l, "\t")
+	if len(f) != 9 {
+		f = strings.Fields(l)
+	}
+	if len(f) != 9 {
+		return s, fmt.Errorf("%d fields, expected 9", len(f))
+	}
+	s.Map = f[1]
+	ints := []*int{&s.Bucket, nil, &s.Width, &s.Height, &s.Start.X, &s.Start.Y, &s.Goal.X, &s.Goal.Y}
+	for i, p := range ints {
+		if p == nil {
+			continue
+		}
+		n, err := strconv.Atoi(f[i])
+		if err != nil {
+			return s, err
+		}
+		*p = n
+	}
+	var err error
+	s.Optimal, err = strconv.ParseFloat(f[8], 64)
+	return s, err
+}
+
+// WriteScen writes problems in the scenario file format.
+func WriteScen(w io.Writer, scens []Scenario) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, "version 1")
+	for _, s := range scens {
+		fmt.Fprintf(out, "%d\t%s\t%d\t%d\t%d\t%d\t%d\t%d\t%.8f\n", s.Bucket, s.Map,
+			s.Width, s.Height, s.Start.X, s.Start.Y, s.Goal.X, s.Goal.Y, s.Optimal)
+	}
+	return out.Flush()
+}
+
+// readErr returns the error of a scanner that stopped early,
+// or an error with the given message if it reached the end.
+func readErr(in *bufio.Scanner, line int, msg string) error {
+	if err := in.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("mapfile: line %d: %s", line, msg)
+}
@@ -0,0 +1,265 @@
+package mapfile
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	"code.google.com/p/eaburns/gridpath"
+)
+
+func readTestdata(t testing.TB) (*Map, []Scenario) {
+	f, err := os.Open("testdata/random.map")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	m, err := ReadMap(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := os.Open("testdata/random.map.scen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	scens, err := ReadScen(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, scens
+}
+
+func TestReadMap(t *testing.T) {
+	m, _ := readTestdata(t)
+	if m.Type != "octile" || m.Width() != 48 || m.Height() != 32 {
+		t.Fatalf("read a %s map of %d×%d, expected octile 48×32", m.Type, m.Width(), m.Height())
+	}
+	for _, test := range []struct {
+		x, y    int
+		blocked bool
+	}{
+		{0, 0, true},   // @
+		{1, 1, false},  // .
+		{4, 1, true},   // W
+		{5, 1, true},   // T
+		{42, 1, false}, // S
+	} {
+		if b := m.Blocked(test.x, test.y); b != test.blocked {
+			t.Errorf("Blocked(%d, %d)=%t, expected %t", test.x, test.y, b, test.blocked)
+		}
+	}
+}
+
+func TestWriteMap(t *testing.T) {
+	want, err := os.ReadFile("testdata/random.map")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, _ := readTestdata(t)
+	var buf bytes.Buffer
+	if err := WriteMap(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("wrote:\n%s\nexpected:\n%s", buf.Bytes(), want)
+	}
+
+	// Any other GridMap is written with '@' and '.' cells.
+	buf.Reset()
+	if err := WriteMap(&buf, gridMap{m}); err != nil {
+		t.Fatal(err)
+	}
+	n, err := ReadMap(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, c := range n.Cells {
+		x, y := i%n.W, i/n.W
+		if c != '.' && c != '@' || n.Blocked(x, y) != m.Blocked(x, y) {
+			t.Errorf("cell %d, %d is %c, blocked=%t", x, y, c, m.Blocked(x, y))
+		}
+	}
+}
+
+func TestWriteMapHandBuilt(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteMap(&buf, &Map{W: 2, H: 1, Cells: []byte(".@")}); err != nil {
+		t.Fatal(err)
+	}
+	m, err := ReadMap(&buf)
+	if err != nil {
+		t.Fatalf("error reading a written map with no Type: %s", err)
+	}
+	if m.Type != "octile" || string(m.Cells) != ".@" {
+		t.Errorf("read a %q map with cells %q, expected an octile map with cells \".@\"", m.Type, m.Cells)
+	}
+
+	for _, m := range []*Map{
+		{W: 2, H: 2, Cells: []byte(".@")},
+		{W: 2, H: 1, Cells: []byte(".@.")},
+		{W: -1, H: -1, Cells: []byte(".")},
+	} {
+		if err := WriteMap(&buf, m); err == nil {
+			t.Errorf("no error writing %d cells in a %d×%d map", len(m.Cells), m.W, m.H)
+		}
+	}
+}
+
+// A gridMap hides the type of a *Map.
+type gridMap struct {
+	gridpath.GridMap
+}
+
+func TestReadMapErrors(t *testing.T) {
+	for _, data := range []string{
+		"",
+		"type octile\nheight 2\nwidth 2\n",
+		"type octile\nheight 2\nwidth x\nmap\n..\n..\n",
+		"type octile\nheight 2\nmap\n..\n..\n",
+		"type octile\ndepth 2\nheight 2\nwidth 2\nmap\n..\n..\n",
+		"type octile\nheight 2\nwidth 2\nmap\n..\n",
+		"type octile\nheight 2\nwidth 2\nmap\n..\n...\n",
+	} {
+		if _, err := ReadMap(strings.NewReader(data)); err == nil {
+			t.Errorf("no error reading %q", data)
+		}
+	}
+
+	m, err := ReadMap(strings.NewReader("type octile\r\nheight 1\r\nwidth 2\r\nmap\r\n.@\r\n"))
+	if err != nil {
+		t.Fatalf("error reading a map with CRLF lines: %s", err)
+	}
+	if m.Blocked(0, 0) || !m.Blocked(1, 0) {
+		t.Errorf("read cells %q, expected \".@\"", m.Cells)
+	}
+}
+
+func TestScen(t *testing.T) {
+	want, err := os.ReadFile("testdata/random.map.scen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, scens := readTestdata(t)
+	if len(scens) != 40 {
+		t.Fatalf("read %d scenarios, expected 40", len(scens))
+	}
+	s := Scenario{10, "random.map", 48, 32, gridpath.Loc{X: 12, Y: 27}, gridpath.Loc{X: 40, Y: 6}, 43.14213562}
+	if scens[0] != s {
+		t.Errorf("read %+v, expected %+v", scens[0], s)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteScen(&buf, scens); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("wrote:\n%s\nexpected:\n%s", buf.Bytes(), want)
+	}
+}
+
+func TestReadScenErrors(t *testing.T) {
+	for _, data := range []string{
+		"version 2\n",
+		"version 1\n0\tx.map\t1\t1\t0\t0\t0\t0\n",
+		"version 1\n0\tx.map\t1\t1\t0\tzero\t0\t0\t0\n",
+		"version 1\n0\tx.map\t1\t1\t0\t0\t0\t0\tinf?\n",
+	} {
+		if _, err := ReadScen(strings.NewReader(data)); err == nil {
+			t.Errorf("no error reading %q", data)
+		}
+	}
+}
+
+// TestOptimal checks the optimal costs of the scenarios, and the
+// costs of the paths found by Astar and JPS, against the costs
+// found by an independent Dijkstra search.
+func TestOptimal(t *testing.T) {
+	m, scens := readTestdata(t)
+	for _, s := range scens {
+		want := dijkstra(m, s.Start, s.Goal)
+		if math.Abs(s.Optimal-want) > 1e-6 {
+			t.Errorf("scenario %v to %v: optimal %.8f, expected %.8f", s.Start, s.Goal, s.Optimal, want)
+		}
+		if _, cost := gridpath.Astar(m, s.Start, s.Goal); math.Abs(cost-want) > 1e-6 {
+			t.Errorf("Astar %v to %v: cost %.8f, expected %.8f", s.Start, s.Goal, cost, want)
+		}
+		if _, cost := gridpath.JPS(m, s.Start, s.Goal); math.Abs(cost-want) > 1e-6 {
+			t.Errorf("JPS %v to %v: cost %.8f, expected %.8f", s.Start, s.Goal, cost, want)
+		}
+	}
+}
+
+// dijkstra returns the cost of the shortest path from start to goal
+// in the map, or -1 if there is none, using the benchmark rules: a
+// diagonal move costs √2 and may not cut the corner of a blocked cell.
+// It is a simple O(n²) Dijkstra search that shares no code with gridpath.
+func dijkstra(m *Map, start, goal gridpath.Loc) float64 {
+	free := func(x, y int) bool {
+		return x >= 0 && x < m.W && y >= 0 && y < m.H && !m.Blocked(x, y)
+	}
+	dist := make([]float64, m.W*m.H)
+	done := make([]bool, m.W*m.H)
+	for i := range dist {
+		dist[i] = math.Inf(1)
+	}
+	dist[start.Y*m.W+start.X] = 0
+	for {
+		u := -1
+		for i := range dist {
+			if !done[i] && !math.IsInf(dist[i], 1) && (u < 0 || dist[i] < dist[u]) {
+				u = i
+			}
+		}
+		if u < 0 {
+			return -1
+		}
+		x, y := u%m.W, u/m.W
+		if x == goal.X && y == goal.Y {
+			return dist[u]
+		}
+		done[u] = true
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				if dx == 0 && dy == 0 || !free(x+dx, y+dy) {
+					continue
+				}
+				c := 1.0
+				if dx != 0 && dy != 0 {
+					if !free(x+dx, y) || !free(x, y+dy) {
+						continue
+					}
+					c = math.Sqrt2
+				}
+				v := (y+dy)*m.W + x + dx
+				dist[v] = math.Min(dist[v], dist[u]+c)
+			}
+		}
+	}
+}
+
+// benchScen benchmarks a search on each of the scenarios,
+// reporting the mean nodes expanded per problem.
+func benchScen(b *testing.B, search func(gridpath.GridMap, gridpath.Loc, gridpath.Loc, *gridpath.Options) ([]gridpath.Loc, float64)) {
+	m, scens := readTestdata(b)
+	b.ResetTimer()
+	expd := 0
+	for i := 0; i < b.N; i++ {
+		s := scens[i%len(scens)]
+		var stats gridpath.SearchStats
+		search(m, s.Start, s.Goal, &gridpath.Options{Stats: &stats})
+		expd += stats.Expanded
+	}
+	b.ReportMetric(float64(expd)/float64(b.N), "expanded/op")
+}
+
+func BenchmarkAstar(b *testing.B) {
+	benchScen(b, gridpath.AstarOptions)
+}
+
+func BenchmarkJPS(b *testing.B) {
+	benchScen(b, gridpath.JPSOptions)
+}
@@ -0,0 +1,341 @@
+package gridpath
+
+import (
+	"container/heap"
+	"math"
+)
+
+// A Planner finds and repairs the shortest path from an agent to a
+// goal as the agent moves and the cells of the map change.  It uses
+// D* Lite (Koenig and Likhachev, 2002), which searches backward from
+// the goal, so that the costs-to-go found by earlier searches remain
+// valid as the agent moves, and only the costs affected by changed
+// cells are recomputed.
+//
+// A Planner uses EightWay movement.  If the map is a CostMap, then
+// the cost of its cells may change too, but never to less than the
+// map's MinCost when the Planner was created.
+type Planner struct {
+	m      GridMap
+	costs  CostMap // m as a CostMap, or nil
+	scale  float64 // The scale of the heuristic
+	stride int
+	start  Loc
+	goal   Loc
+
+	// last is the start at the time of the last search or change,
+	// and km is the sum of the heuristic distances that the start
+	// moved between them, which is added to the keys of new nodes
+	// so that the keys of older nodes remain lower bounds and
+	// needn't be updated.
+	last Loc
+	km   float64
+
+	nodes []dnode
+	open  dqueue
+	expd  int
+}
+
+// A dnode is a D* Lite node.
+type dnode struct {
+	ind     int        // The index of this node.
+	pqindex int        // The priority queue index of the node, -1 indicates not in the queue.
+	g       float64    // The cost from this node to the goal, as of its last expansion.
+	rhs     float64    // The one-step lookahead cost from this node to the goal.
+	key     [2]float64 // The priority of the node in the queue.
+}
+
+// NewPlanner returns a new Planner for paths from start to goal in
+// the given map.  The first path is found when Path is called.
+func NewPlanner(m GridMap, start, goal Loc) *Planner {
+	m, costs, scale := costGrid(m)
+	p := &Planner{
+		m:      m,
+		costs:  costs,
+		scale:  scale,
+		stride: m.Height(),
+		start:  start,
+		goal:   goal,
+		last:   start,
+		nodes:  make([]dnode, m.Width()*m.Height()),
+	}
+	for i := range p.nodes {
+		n := &p.nodes[i]
+		n.ind = i
+		n.pqindex = -1
+		n.g = math.Inf(1)
+		n.rhs = math.Inf(1)
+	}
+	g := &p.nodes[p.index(goal)]
+	g.rhs = 0
+	g.key = p.calcKey(g)
+	heap.Push(&p.open, g)
+	return p
+}
+
+// MoveTo moves the start of the path, the agent's location, to
+// the given cell.  The agent needn't move along the path.
+func (p *Planner) MoveTo(l Loc) {
+	p.start = l
+}
+
+// NotifyChanged notifies the Planner that the given cells of the map
+// have become blocked or unblocked, or have changed in cost.  Only
+// the costs-to-go that depend on the cells are recomputed, when the
+// path is next requested.
+func (p *Planner) NotifyChanged(cells []Loc) {
+	p.km += p.h(p.last, p.start)
+	p.last = p.start
+
+	// The moves out of a cell depend on the cell, its
+	// neighbors, and the corners of its diagonal moves,
+	// which are also its neighbors.  So a change to a cell
+	// affects the moves out of the cell and its neighbors.
+	for _, l := range cells {
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				x, y := l.X+dx, l.Y+dy
+				if x < 0 || x >= p.m.Width() || y < 0 || y >= p.m.Height() {
+					continue
+				}
+				n := &p.nodes[p.index(Loc{x, y})]
+				p.updateRHS(n, x, y)
+				p.updateNode(n)
+			}
+		}
+	}
+}
+
+// Path returns the shortest path from the start to the goal and its
+// cost, repairing the previous search to account for the changes
+// and moves since it was last called.  As with Astar, the path runs
+// from the goal back to the cell after the start.  If there is no
+// path then the path is nil and the cost is -1.
+func (p *Planner) Path() ([]Loc, float64) {
+	p.km += p.h(p.last, p.start)
+	p.last = p.start
+
+	start := &p.nodes[p.index(p.start)]
+	for {
+		p.computeShortestPath(start)
+		if math.IsInf(start.g, 1) {
+			return nil, -1
+		}
+		path, n, ok := p.walk()
+		switch {
+		case !ok:
+			return nil, -1
+		case n == nil:
+			return path, start.g
+		}
+		// The cost-to-go of a node on the path is out of
+		// date, so search until it is up to date, then walk
+		// the path again from the start.
+		p.computeShortestPath(n)
+	}
+}
+
+// walk returns the path from the start to the goal, following
+// moves to successors whose cost-to-go is consistent and along
+// a shortest path.  If the only such move is to a node that is
+// not consistent, walk returns that node instead, and the node
+// must be made consistent before the path can be known.  If
+// there is no such move then the map changed without notice,
+// and walk returns false.
+//
+// The start must be consistent and have an up-to-date cost-to-go.
+// Since the cost-to-go of a consistent node is the least cost of
+// a move to a successor plus its cost-to-go, each move along the
+// path decreases the cost-to-go by the cost of the move, and the
+// cost of the path is the cost-to-go of the start.
+func (p *Planner) walk() ([]Loc, *dnode, bool) {
+	var path []Loc
+	for l := p.start; l != p.goal; {
+		// The g values strictly decrease along the path,
+		// but guard against cycling on a map that changed
+		// without notice.
+		if len(path) >= len(p.nodes) {
+			return nil, nil, false
+		}
+		g := p.nodes[p.index(l)].g
+		next, stale := Loc{-1, -1}, (*dnode)(nil)
+		p.succs(l.X, l.Y, func(x, y int, c float64) {
+			n := &p.nodes[p.index(Loc{x, y})]
+			switch {
+			case next.X >= 0 || c+n.g != g:
+				return
+			case n.g == n.rhs:
+				next = Loc{x, y}
+			case stale == nil:
+				stale = n
+			}
+		})
+		switch {
+		case next.X >= 0:
+			path = append(path, next)
+			l = next
+		case stale != nil:
+			return nil, stale, true
+		default:
+			return nil, nil, false
+		}
+	}
+
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path, nil, true
+}
+
+// computeShortestPath expands nodes until the node s is consistent
+// and no node in the queue can lower its cost-to-go, at which point
+// the cost-to-go of s is up to date.
+func (p *Planner) computeShortestPath(s *dnode) {
+	for len(p.open) > 0 && (less(p.open[0].key, p.calcKey(s)) || s.rhs != s.g) {
+		n := p.open[0]
+		x, y := n.ind/p.stride, n.ind%p.stride
+		if k := p.calcKey(n); less(n.key, k) {
+			n.key = k
+			heap.Fix(&p.open, 0)
+			continue
+		}
+
+		p.expd++
+
+		heap.Pop(&p.open)
+		if n.g > n.rhs {
+			n.g = n.rhs
+			p.preds(x, y, func(x, y int, c float64) {
+				pred := &p.nodes[p.index(Loc{x, y})]
+				if pred != n && c+n.g < pred.rhs && pred.ind != p.index(p.goal) {
+					pred.rhs = c + n.g
+					p.updateNode(pred)
+				}
+			})
+			continue
+		}
+		n.g = math.Inf(1)
+		p.updateRHS(n, x, y)
+		p.updateNode(n)
+		p.preds(x, y, func(x, y int, _ float64) {
+			pred := &p.nodes[p.index(Loc{x, y})]
+			p.updateRHS(pred, x, y)
+			p.updateNode(pred)
+		})
+	}
+}
+
+// updateRHS recomputes the one-step lookahead
+// cost of the node n at x, y.
+func (p *Planner) updateRHS(n *dnode, x, y int) {
+	if n.ind == p.index(p.goal) {
+		return
+	}
+	n.rhs = math.Inf(1)
+	p.succs(x, y, func(x, y int, c float64) {
+		n.rhs = math.Min(n.rhs, c+p.nodes[p.index(Loc{x, y})].g)
+	})
+}
+
+// updateNode adds a locally inconsistent node to
+// the queue, or removes a consistent one from it.
+func (p *Planner) updateNode(n *dnode) {
+	switch {
+	case n.g != n.rhs && n.pqindex >= 0:
+		n.key = p.calcKey(n)
+		heap.Fix(&p.open, n.pqindex)
+	case n.g != n.rhs:
+		n.key = p.calcKey(n)
+		heap.Push(&p.open, n)
+	case n.pqindex >= 0:
+		heap.Remove(&p.open, n.pqindex)
+	}
+}
+
+// calcKey returns the priority of a node.
+func (p *Planner) calcKey(n *dnode) [2]float64 {
+	k := math.Min(n.g, n.rhs)
+	l := Loc{n.ind / p.stride, n.ind % p.stride}
+	return [2]float64{k + p.h(p.start, l) + p.km, k}
+}
+
+// h returns the heuristic estimate of the cost between two cells.
+func (p *Planner) h(a, b Loc) float64 {
+	return octiledist(a.X, a.Y, b.X, b.Y) * p.scale
+}
+
+// succs calls f on each cell reachable by a move from x, y
+// and the cost of the move.
+func (p *Planner) succs(x, y int, f func(x, y int, c float64)) {
+	if !clear(p.m, x, y) {
+		return
+	}
+	for _, mv := range moves {
+		if !mv.ok(p.m, x, y) {
+			continue
+		}
+		kidx, kidy := x+mv.dx, y+mv.dy
+		f(kidx, kidy, p.cost(mv, kidx, kidy))
+	}
+}
+
+// preds calls f on each cell from which x, y is reachable by a
+// move and the cost of the move.  Since EightWay moves are
+// symmetric, these are the cells reachable from x, y.
+func (p *Planner) preds(x, y int, f func(x, y int, c float64)) {
+	if !clear(p.m, x, y) {
+		return
+	}
+	for _, mv := range moves {
+		if mv.ok(p.m, x, y) {
+			f(x+mv.dx, y+mv.dy, p.cost(mv, x, y))
+		}
+	}
+}
+
+// cost returns the cost of the move into x, y.
+func (p *Planner) cost(mv move, x, y int) float64 {
+	if p.costs == nil {
+		return mv.cost
+	}
+	return mv.cost * p.costs.Cost(x, y)
+}
+
+func (p *Planner) index(l Loc) int {
+	return l.X*p.stride + l.Y
+}
+
+// less returns true if key a is lexicographically less than key b.
+func less(a, b [2]float64) bool {
+	return a[0] < b[0] || a[0] == b[0] && a[1] < b[1]
+}
+
+// dqueue is a priority queue of D* Lite nodes.
+type dqueue []*dnode
+
+func (q dqueue) Len() int {
+	return len(q)
+}
+
+func (q dqueue) Less(i, j int) bool {
+	return less(q[i].key, q[j].key)
+}
+
+func (q dqueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].pqindex = i
+	q[j].pqindex = j
+}
+
+func (q *dqueue) Push(n interface{}) {
+	*q = append(*q, n.(*dnode))
+	(*q)[len(*q)-1].pqindex = len(*q) - 1
+}
+
+func (q *dqueue) Pop() interface{} {
+	old := *q
+	n := old[len(old)-1]
+	n.pqindex = -1
+	*q = old[:len(old)-1]
+	return n
+}
@@ -0,0 +1,204 @@
+package gridpath
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// checkPlanner fails the test if the planner's path does not
+// have the same cost as a new A* search on the map.
+func checkPlanner(t *testing.T, p *Planner, m GridMap) ([]Loc, float64) {
+	a := astar(m, EightWay, p.start, p.goal, nil)
+	want := a.closed[a.goali].g
+	path, cost := p.Path()
+	if math.Abs(cost-want) > 1e-9 {
+		t.Fatalf("%v to %v: cost %g, expected %g", p.start, p.goal, cost, want)
+	}
+	if cost >= 0 {
+		checkPath(t, m, EightWay, p.start, p.goal, path, cost)
+	}
+	return path, cost
+}
+
+func TestPlanner(t *testing.T) {
+	r := rand.New(rand.NewSource(0))
+	for _, weighted := range []bool{false, true} {
+		for n := 0; n < 20; n++ {
+			m := randomMap(r, 24, 16, 0.2, weighted)
+			var blkd []bool
+			var cost []float64
+			switch m := m.(type) {
+			case gridmap:
+				blkd = m.blkd
+			case costmap:
+				blkd, cost = m.blkd, m.cost
+			}
+			start, goal := Loc{0, 0}, Loc{23, 15}
+			blkd[0], blkd[len(blkd)-1] = false, false
+
+			p := NewPlanner(m, start, goal)
+			for step := 0; step < 40 && p.start != goal; step++ {
+				path, c := checkPlanner(t, p, m)
+				if c >= 0 {
+					p.MoveTo(path[len(path)-1])
+				}
+
+				// Change some cells, but never the agent's.
+				var changed []Loc
+				for i := 0; i < 3; i++ {
+					l := Loc{r.Intn(24), r.Intn(16)}
+					if l == p.start || l == goal {
+						continue
+					}
+					j := l.X*16 + l.Y
+					blkd[j] = !blkd[j]
+					if cost != nil {
+						cost[j] = float64(1 + r.Intn(5))
+					}
+					changed = append(changed, l)
+				}
+				p.NotifyChanged(changed)
+			}
+		}
+	}
+}
+
+func TestPlannerNoPath(t *testing.T) {
+	m, err := loadGridMap("1.map")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := NewPlanner(m, m.start, m.goal)
+	checkPlanner(t, p, m)
+
+	// Wall off the goal, then open it again.
+	var wall []Loc
+	for y := 0; y < m.h; y++ {
+		wall = append(wall, Loc{2, y})
+	}
+	for _, l := range wall {
+		m.blkd[l.X*m.h+l.Y] = true
+	}
+	p.NotifyChanged(wall)
+	if path, cost := checkPlanner(t, p, m); path != nil || cost != -1 {
+		t.Errorf("got path %v, cost %g with the goal walled off", path, cost)
+	}
+	m.blkd[2*m.h+5] = false
+	p.NotifyChanged([]Loc{{2, 5}})
+	checkPlanner(t, p, m)
+}
+
+// TestPlannerRepair checks that repairing a path after a small
+// change expands fewer nodes than planning again from scratch.
+func TestPlannerRepair(t *testing.T) {
+	m, start, goal := benchMap(0.2)
+	p := NewPlanner(m, start, goal)
+	path, _ := checkPlanner(t, p, m)
+
+	for i := 0; i < 10; i++ {
+		p.MoveTo(path[len(path)-1])
+		path = path[:len(path)-1]
+	}
+	block := path[len(path)/2]
+	m.(gridmap).blkd[block.X*256+block.Y] = true
+	p.NotifyChanged([]Loc{block})
+
+	before := p.expd
+	checkPlanner(t, p, m)
+	repair := p.expd - before
+
+	q := NewPlanner(m, p.start, goal)
+	checkPlanner(t, q, m)
+	if repair >= q.expd {
+		t.Errorf("repair expanded %d nodes, planning from scratch expanded %d", repair, q.expd)
+	}
+}
+
+// TestPlannerRandom compares the Planner with A* at every step on
+// small, dense random maps, on which the agent sometimes teleports
+// and the cells next to the agent change.
+func TestPlannerRandom(t *testing.T) {
+	const w, h = 12, 9
+	r := rand.New(rand.NewSource(0))
+	for _, weighted := range []bool{false, true} {
+		for _, teleport := range []bool{false, true} {
+			for n := 0; n < 250; n++ {
+				m := randomMap(r, w, h, 0.25, weighted)
+				var blkd []bool
+				var cost []float64
+				switch m := m.(type) {
+				case gridmap:
+					blkd = m.blkd
+				case costmap:
+					blkd, cost = m.blkd, m.cost
+				}
+				free := func() Loc {
+					for {
+						l := Loc{r.Intn(w), r.Intn(h)}
+						if !blkd[l.X*h+l.Y] {
+							return l
+						}
+					}
+				}
+				goal := free()
+				p := NewPlanner(m, free(), goal)
+				for step := 0; step < 20 && p.start != goal; step++ {
+					path, c := checkPlanner(t, p, m)
+					switch {
+					case teleport && r.Intn(3) == 0:
+						p.MoveTo(free())
+					case c >= 0:
+						p.MoveTo(path[len(path)-1])
+					}
+
+					// Change some cells, often next to the
+					// agent, but never the agent's or the goal's.
+					var changed []Loc
+					for i := 0; i < 3; i++ {
+						l := Loc{r.Intn(w), r.Intn(h)}
+						if r.Intn(2) == 0 {
+							l = Loc{p.start.X + r.Intn(3) - 1, p.start.Y + r.Intn(3) - 1}
+						}
+						if l.X < 0 || l.X >= w || l.Y < 0 || l.Y >= h || l == p.start || l == goal {
+							continue
+						}
+						j := l.X*h + l.Y
+						blkd[j] = !blkd[j]
+						if cost != nil {
+							cost[j] = float64(1 + r.Intn(5))
+						}
+						changed = append(changed, l)
+					}
+					p.NotifyChanged(changed)
+				}
+			}
+		}
+	}
+}
+
+// TestPlannerMoveTo compares the Planner with A* as the agent moves
+// to random cells of larger maps that never change, so that only the
+// moves, not changes to the map, make the queued keys out of date.
+func TestPlannerMoveTo(t *testing.T) {
+	const w, h = 40, 30
+	r := rand.New(rand.NewSource(0))
+	for _, weighted := range []bool{false, true} {
+		for n := 0; n < 20; n++ {
+			m := randomMap(r, w, h, 0.3, weighted)
+			free := func() Loc {
+				for {
+					l := Loc{r.Intn(w), r.Intn(h)}
+					if !m.Blocked(l.X, l.Y) {
+						return l
+					}
+				}
+			}
+			p := NewPlanner(m, free(), free())
+			for step := 0; step < 50; step++ {
+				checkPlanner(t, p, m)
+				p.MoveTo(free())
+			}
+		}
+	}
+}
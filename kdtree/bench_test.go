@@ -5,13 +5,19 @@ import (
 	"testing"
 )
 
+// Point2 and tree2 are the point and tree types used by the benchmarks.
+type (
+	point2 = [2]float64
+	tree2  = Tree[point2, struct{}]
+)
+
 // RadiusMax is the maximum radius for InRange benchmarks.
 const radiusMax = 0.1
 
 // BenchmarkInsert benchmarks insertions into an initially empty tree.
 func BenchmarkInsert(b *testing.B) {
 	b.StopTimer()
-	pts := make([]Point, b.N)
+	pts := make([]point2, b.N)
 	for i := range pts {
 		for j := range pts[i] {
 			pts[i][j] = rand.Float64()
@@ -19,9 +25,9 @@ func BenchmarkInsert(b *testing.B) {
 	}
 
 	b.StartTimer()
-	var t *T
+	var t *tree2
 	for i := range pts {
-		t = t.Insert(&T{Point: pts[i]})
+		t = t.Insert(&tree2{Point: pts[i]})
 	}
 }
 
@@ -38,7 +44,7 @@ func BenchmarkInsert500(b *testing.B) {
 // InsertSz benchmarks inserting sz nodes into an empty tree.
 func insertSz(sz int, b *testing.B) {
 	b.StopTimer()
-	pts := make([]Point, sz)
+	pts := make([]point2, sz)
 	for i := range pts {
 		for j := range pts[i] {
 			pts[i][j] = rand.Float64()
@@ -47,9 +53,9 @@ func insertSz(sz int, b *testing.B) {
 
 	b.StartTimer()
 	for i := 0; i < b.N; i++ {
-		var t *T
+		var t *tree2
 		for i := range pts {
-			t = t.Insert(&T{Point: pts[i]})
+			t = t.Insert(&tree2{Point: pts[i]})
 		}
 	}
 
@@ -68,8 +74,14 @@ func BenchmarkMake500(b *testing.B) {
 // MakeSz benchmarks Make with a given number of nodes.
 // The time includes allocating the nodes.
 func makeSz(sz int, b *testing.B) {
+	makeSzOptions(sz, DefaultBuildOptions(), b)
+}
+
+// MakeSzOptions benchmarks Make with a given number of nodes
+// and the given BuildOptions.
+func makeSzOptions(sz int, opts BuildOptions, b *testing.B) {
 	b.StopTimer()
-	pts := make([]Point, sz)
+	pts := make([]point2, sz)
 	for i := range pts {
 		for j := range pts[i] {
 			pts[i][j] = rand.Float64()
@@ -77,19 +89,51 @@ func makeSz(sz int, b *testing.B) {
 	}
 
 	b.StartTimer()
-	nodes := make([]T, sz)
-	nodeps := make([]*T, sz)
+	nodes := make([]tree2, sz)
+	nodeps := make([]*tree2, sz)
 	for i := range nodes {
 		nodes[i].Point = pts[i]
 		nodeps[i] = &nodes[i]
 	}
 
 	for i := 0; i < b.N; i++ {
-		New(nodeps)
+		NewOptions(nodeps, opts)
 	}
 
 }
 
+// BenchmarkMake100000Serial benchmarks Make with 100000 nodes,
+// building the tree without concurrency.
+func BenchmarkMake100000Serial(b *testing.B) {
+	makeSzParallel(100000, 0, b)
+}
+
+// BenchmarkMake100000Parallel benchmarks Make with 100000 nodes,
+// building the tree concurrently.
+func BenchmarkMake100000Parallel(b *testing.B) {
+	makeSzParallel(100000, 4, b)
+}
+
+// BenchmarkMake1000000Serial benchmarks Make with 1000000 nodes,
+// building the tree without concurrency.
+func BenchmarkMake1000000Serial(b *testing.B) {
+	makeSzParallel(1000000, 0, b)
+}
+
+// BenchmarkMake1000000Parallel benchmarks Make with 1000000 nodes,
+// building the tree concurrently.
+func BenchmarkMake1000000Parallel(b *testing.B) {
+	makeSzParallel(1000000, 4, b)
+}
+
+// MakeSzParallel benchmarks Make with a given number of nodes
+// and the given ParallelDepth.
+func makeSzParallel(sz, depth int, b *testing.B) {
+	opts := DefaultBuildOptions()
+	opts.ParallelDepth = depth
+	makeSzOptions(sz, opts, b)
+}
+
 func BenchmarkMakeInRange1000(b *testing.B) {
 	newInRangeSz(1000, b)
 }
@@ -102,8 +146,8 @@ func BenchmarkMakeInRange500(b *testing.B) {
 // created with New with the given number of nodes.
 func newInRangeSz(sz int, b *testing.B) {
 	b.StopTimer()
-	nodes := make([]T, sz)
-	nodeps := make([]*T, sz)
+	nodes := make([]tree2, sz)
+	nodeps := make([]*tree2, sz)
 	for i := range nodes {
 		for j := range nodes[i].Point {
 			nodes[i].Point[j] = rand.Float64()
@@ -112,7 +156,7 @@ func newInRangeSz(sz int, b *testing.B) {
 	}
 	tree := New(nodeps)
 
-	points := make([]Point, b.N)
+	points := make([]point2, b.N)
 	for i := range points {
 		for j := range points[i] {
 			points[i][j] = rand.Float64()
@@ -123,11 +167,46 @@ func newInRangeSz(sz int, b *testing.B) {
 		rs[i] = rand.Float64()
 	}
 
-	pool := make([]*T, 0, sz)
+	pool := make([]*tree2, 0, sz)
 
 	b.StartTimer()
 	for i, pt := range points {
-		tree.InRange(pt, rs[i]*radiusMax, pool[:0])
+		tree.InRange(pt, rs[i]*radiusMax, nil, pool[:0])
+	}
+}
+
+func BenchmarkMakeInBox1000(b *testing.B) {
+	newInBoxSz(1000, b)
+}
+
+// newInBoxSz benchmarks InBox function on a tree
+// created with New with the given number of nodes.
+func newInBoxSz(sz int, b *testing.B) {
+	b.StopTimer()
+	nodes := make([]tree2, sz)
+	nodeps := make([]*tree2, sz)
+	for i := range nodes {
+		for j := range nodes[i].Point {
+			nodes[i].Point[j] = rand.Float64()
+		}
+		nodeps[i] = &nodes[i]
+	}
+	tree := New(nodeps)
+
+	mins := make([]point2, b.N)
+	maxs := make([]point2, b.N)
+	for i := range mins {
+		for j := range mins[i] {
+			mins[i][j] = rand.Float64()
+			maxs[i][j] = mins[i][j] + rand.Float64()*radiusMax
+		}
+	}
+
+	pool := make([]*tree2, 0, sz)
+
+	b.StartTimer()
+	for i := range mins {
+		tree.InBox(mins[i], maxs[i], pool[:0])
 	}
 }
 
@@ -144,16 +223,16 @@ func BenchmarkInsertInRange500(b *testing.B) {
 // of nodes.
 func insertInRangeSz(sz int, b *testing.B) {
 	b.StopTimer()
-	var tree *T
+	var tree *tree2
 	for i := 0; i < sz; i++ {
-		n := new(T)
+		n := new(tree2)
 		for j := range n.Point {
 			n.Point[j] = rand.Float64()
 		}
 		tree = tree.Insert(n)
 	}
 
-	points := make([]Point, b.N)
+	points := make([]point2, b.N)
 	for i := range points {
 		for j := range points[i] {
 			points[i][j] = rand.Float64()
@@ -164,11 +243,11 @@ func insertInRangeSz(sz int, b *testing.B) {
 		rs[i] = rand.Float64()
 	}
 
-	pool := make([]*T, 0, sz)
+	pool := make([]*tree2, 0, sz)
 
 	b.StartTimer()
 	for i, pt := range points {
-		tree.InRange(pt, rs[i]*radiusMax, pool[:0])
+		tree.InRange(pt, rs[i]*radiusMax, nil, pool[:0])
 	}
 }
 
@@ -182,14 +261,14 @@ func BenchmarkInRangeLinear1000(b *testing.B) {
 // a linear scan of the given number of nodes.
 func inRangeLinearSz(sz int, b *testing.B) {
 	b.StopTimer()
-	nodes := make([]T, sz)
+	nodes := make([]tree2, sz)
 	for i := range nodes {
 		for j := range nodes[i].Point {
 			nodes[i].Point[j] = rand.Float64()
 		}
 	}
 
-	points := make([]Point, b.N)
+	points := make([]point2, b.N)
 	for i := range points {
 		for j := range points[i] {
 			points[i][j] = rand.Float64()
@@ -200,16 +279,49 @@ func inRangeLinearSz(sz int, b *testing.B) {
 		rs[i] = rand.Float64() * radiusMax
 	}
 
-	local := make([]*T, 0, sz)
+	local := make([]*tree2, 0, sz)
 
 	b.StartTimer()
 	for i, pt := range points {
 		local = local[:0]
 		rr := rs[i] * rs[i]
 		for i := range nodes {
-			if nodes[i].Point.sqDist(&pt) < rr {
+			if sqDist(&nodes[i].Point, &pt) < rr {
 				local = append(local, &nodes[i])
 			}
 		}
 	}
 }
+
+func BenchmarkKNearest1000(b *testing.B) {
+	kNearestSz(1000, 10, b)
+}
+
+// kNearestSz benchmarks the KNearest function on a tree
+// created with New with the given number of nodes.
+func kNearestSz(sz, k int, b *testing.B) {
+	b.StopTimer()
+	nodes := make([]tree2, sz)
+	nodeps := make([]*tree2, sz)
+	for i := range nodes {
+		for j := range nodes[i].Point {
+			nodes[i].Point[j] = rand.Float64()
+		}
+		nodeps[i] = &nodes[i]
+	}
+	tree := New(nodeps)
+
+	points := make([]point2, b.N)
+	for i := range points {
+		for j := range points[i] {
+			points[i][j] = rand.Float64()
+		}
+	}
+
+	pool := make([]*tree2, 0, k)
+
+	b.StartTimer()
+	for _, pt := range points {
+		tree.KNearest(pt, k, nil, pool[:0])
+	}
+}
@@ -0,0 +1,429 @@
+package kdtree
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// The binary format of a K-D tree is a header followed by the nodes
+// of the tree in pre-order.  All integers are little-endian.
+//
+// The header is:
+//	magic   [4]byte  "KDT\x00"
+//	version uint8    formatVersion
+//	dims    uint8    the dimensionality of the tree's points
+//	count   uint64   the number of nodes
+//
+// Each node is:
+//	split   uint8    the splitting dimension
+//	kids    uint8    bit 0 is set if there is a left child,
+//	                 bit 1 is set if there is a right child
+//	point   [dims]float64
+//	size    uvarint  the number of bytes of encoded data
+//	data    [size]byte
+//
+// The left subtree of a node, if any, follows the node,
+// and the right subtree, if any, follows the left.
+
+const (
+	magic         = "KDT\x00"
+	formatVersion = 1
+
+	hasLeft  = 1 << 0
+	hasRight = 1 << 1
+
+	// MinNodeBlock is the minimum number of nodes
+	// allocated at a time when reading a tree.
+	minNodeBlock = 64
+
+	// MinReadStep is the minimum number of bytes by
+	// which the read buffer grows at a time.
+	minReadStep = 4096
+)
+
+// ErrFormat is returned when reading a K-D tree that is not
+// in the binary format written by WriteTo.
+var ErrFormat = errors.New("kdtree: invalid format")
+
+// A Codec encodes and decodes the Data of K-D tree nodes.
+type Codec[D any] interface {
+	// Marshal returns the encoding of the data.
+	Marshal(D) ([]byte, error)
+
+	// Unmarshal decodes data that was encoded by Marshal.
+	// The byte slice is only valid until Unmarshal returns.
+	Unmarshal([]byte, *D) error
+}
+
+// GobCodec is a Codec that encodes Data using encoding/gob.
+// It is the Codec used by WriteTo and ReadFrom.
+//
+// A GobCodec uses a single gob stream for all of the Data of a tree,
+// so that the gob type information is only written once.  As such,
+// a GobCodec may only be used for a single call to Write or Read.
+type GobCodec[D any] struct {
+	enc  *gob.Encoder
+	ebuf bytes.Buffer
+	dec  *gob.Decoder
+	dbuf bytes.Buffer
+}
+
+// Marshal implements the Marshal method of the Codec interface.
+func (c *GobCodec[D]) Marshal(d D) ([]byte, error) {
+	if c.enc == nil {
+		c.enc = gob.NewEncoder(&c.ebuf)
+	}
+	c.ebuf.Reset()
+	if err := c.enc.Encode(d); err != nil {
+		return nil, err
+	}
+	return c.ebuf.Bytes(), nil
+}
+
+// Unmarshal implements the Unmarshal method of the Codec interface.
+func (c *GobCodec[D]) Unmarshal(data []byte, d *D) error {
+	if c.dec == nil {
+		c.dec = gob.NewDecoder(&c.dbuf)
+	}
+	c.dbuf.Reset()
+	c.dbuf.Write(data)
+	if err := c.dec.Decode(d); err != nil {
+		return err
+	}
+	if c.dbuf.Len() != 0 {
+		return fmt.Errorf("%w: %d bytes of data unread", ErrFormat, c.dbuf.Len())
+	}
+	return nil
+}
+
+// WriteTo implements the io.WriterTo interface, writing the K-D tree
+// in a binary format that preserves its structure.  Data is encoded
+// using GobCodec.
+func (t *Tree[P, D]) WriteTo(w io.Writer) (int64, error) {
+	return Write(w, t, &GobCodec[D]{})
+}
+
+// ReadFrom implements the io.ReaderFrom interface, reading a non-empty
+// K-D tree written by WriteTo into t, which becomes the root of the tree.
+// Data is decoded using GobCodec.
+func (t *Tree[P, D]) ReadFrom(r io.Reader) (int64, error) {
+	cr := &countReader{r: byteReader(r)}
+	root, err := Read[P, D](cr, &GobCodec[D]{})
+	if err == nil && root == nil {
+		err = errors.New("kdtree: read an empty tree")
+	}
+	if err != nil {
+		return cr.n, err
+	}
+	*t = *root
+	return cr.n, nil
+}
+
+// Write writes the K-D tree in a binary format that preserves its
+// structure, encoding Data using the given Codec.  The number of
+// bytes written is returned.
+func Write[P Point, D any](w io.Writer, t *Tree[P, D], c Codec[D]) (int64, error) {
+	cw := &countWriter{w: bufio.NewWriter(w)}
+	e := encoder[P, D]{w: cw, c: c}
+	e.header(t.count())
+	e.tree(t)
+	if e.err == nil {
+		e.err = cw.w.Flush()
+	}
+	return cw.n, e.err
+}
+
+// Read returns a K-D tree read from the binary format written by Write,
+// decoding Data using the given Codec.  The tree has the same structure
+// as the tree that was written, so it needn't be re-balanced.
+//
+// If r does not implement io.ByteReader then it is buffered,
+// and Read may read more bytes from r than the tree's encoding.
+func Read[P Point, D any](r io.Reader, c Codec[D]) (*Tree[P, D], error) {
+	d := decoder[P, D]{r: byteReader(r), c: c}
+	d.n = d.header()
+	if d.err != nil {
+		return nil, d.err
+	}
+	var t *Tree[P, D]
+	if d.n > 0 {
+		t = d.tree()
+		if d.err == nil && d.n > 0 {
+			d.err = fmt.Errorf("%w: %d nodes unread", ErrFormat, d.n)
+		}
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	return t, nil
+}
+
+type encoder[P Point, D any] struct {
+	w   *countWriter
+	c   Codec[D]
+	buf []byte
+	err error
+}
+
+func (e *encoder[P, D]) header(n int) {
+	e.buf = append(e.buf[:0], magic...)
+	e.buf = append(e.buf, formatVersion, byte(dims[P]()))
+	e.buf = binary.LittleEndian.AppendUint64(e.buf, uint64(n))
+	e.write()
+}
+
+func (e *encoder[P, D]) tree(t *Tree[P, D]) {
+	if t == nil || e.err != nil {
+		return
+	}
+	var kids byte
+	if t.left != nil {
+		kids |= hasLeft
+	}
+	if t.right != nil {
+		kids |= hasRight
+	}
+	e.buf = append(e.buf[:0], byte(t.split), kids)
+	for i := 0; i < len(t.Point); i++ {
+		e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(t.Point[i]))
+	}
+	data, err := e.c.Marshal(t.Data)
+	if err != nil {
+		e.err = err
+		return
+	}
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(data)))
+	e.buf = append(e.buf, data...)
+	e.write()
+
+	e.tree(t.left)
+	e.tree(t.right)
+}
+
+func (e *encoder[P, D]) write() {
+	if e.err == nil {
+		_, e.err = e.w.Write(e.buf)
+	}
+}
+
+type decoder[P Point, D any] struct {
+	r   readByter
+	c   Codec[D]
+	buf []byte
+	err error
+
+	// N is the number of nodes remaining to be read.
+	n int
+	// Nodes are allocated, but not yet read, nodes.
+	nodes []Tree[P, D]
+	// Alloc is the number of nodes allocated so far.
+	alloc int
+}
+
+// Header reads the header, returning the number of nodes.
+func (d *decoder[P, D]) header() int {
+	b := d.read(len(magic) + 2 + 8)
+	switch {
+	case d.err != nil:
+		return 0
+	case string(b[:len(magic)]) != magic:
+		d.err = ErrFormat
+		return 0
+	case b[len(magic)] != formatVersion:
+		d.err = fmt.Errorf("%w: unknown version %d", ErrFormat, b[len(magic)])
+		return 0
+	case int(b[len(magic)+1]) != dims[P]():
+		d.err = fmt.Errorf("%w: %d dimensions, want %d", ErrFormat, b[len(magic)+1], dims[P]())
+		return 0
+	}
+	n := binary.LittleEndian.Uint64(b[len(magic)+2:])
+	if n > math.MaxInt32 {
+		d.err = fmt.Errorf("%w: too many nodes", ErrFormat)
+		return 0
+	}
+	return int(n)
+}
+
+// Tree reads a tree.  The nodes are read iteratively, rather than
+// recursively, so that a deeply nested input cannot exhaust the stack.
+func (d *decoder[P, D]) tree() *Tree[P, D] {
+	var root *Tree[P, D]
+	// Kids are the child pointers of nodes whose subtrees are yet
+	// to be read, with the next to be read at the end.  A node adds
+	// at most two, so kids grows at most with the nodes read.
+	kids := []**Tree[P, D]{&root}
+	for len(kids) > 0 && d.err == nil {
+		kid := kids[len(kids)-1]
+		kids = kids[:len(kids)-1]
+		t := d.node()
+		if t == nil {
+			return nil
+		}
+		*kid = t
+		flags := d.readNode(t)
+		// The left subtree precedes the right in the input.
+		if flags&hasRight != 0 {
+			kids = append(kids, &t.right)
+		}
+		if flags&hasLeft != 0 {
+			kids = append(kids, &t.left)
+		}
+	}
+	if d.err != nil {
+		return nil
+	}
+	return root
+}
+
+// ReadNode reads the split dimension, point, and data of a node
+// into t, returning the node's flags for which kids it has.
+func (d *decoder[P, D]) readNode(t *Tree[P, D]) byte {
+	k := dims[P]()
+	b := d.read(2 + 8*k)
+	if d.err != nil {
+		return 0
+	}
+	split, kids := b[0], b[1]
+	if int(split) >= k || kids&^(hasLeft|hasRight) != 0 {
+		d.err = ErrFormat
+		return 0
+	}
+	t.split = int(split)
+	for i := 0; i < k; i++ {
+		t.Point[i] = math.Float64frombits(binary.LittleEndian.Uint64(b[2+8*i:]))
+	}
+
+	size, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		d.err = unexpectedEOF(err)
+		return 0
+	}
+	if size > math.MaxInt32 {
+		d.err = fmt.Errorf("%w: data too large", ErrFormat)
+		return 0
+	}
+	if data := d.read(int(size)); d.err == nil {
+		d.err = d.c.Unmarshal(data, &t.Data)
+	}
+	return kids
+}
+
+// Node returns a new node, or nil if all of the nodes
+// counted in the header have been read.
+//
+// The node count in the header is not trusted to size a single
+// allocation.  Instead, nodes are allocated in blocks that double
+// in size, so the memory allocated is proportional to the number
+// of nodes actually read.
+func (d *decoder[P, D]) node() *Tree[P, D] {
+	if d.n == 0 {
+		d.err = fmt.Errorf("%w: too many nodes", ErrFormat)
+		return nil
+	}
+	if len(d.nodes) == 0 {
+		n := d.alloc
+		if n < minNodeBlock {
+			n = minNodeBlock
+		}
+		if n > d.n {
+			n = d.n
+		}
+		d.nodes = make([]Tree[P, D], n)
+		d.alloc += n
+	}
+	t := &d.nodes[0]
+	d.nodes = d.nodes[1:]
+	d.n--
+	return t
+}
+
+// Read returns the next n bytes.  The returned slice
+// is only valid until the next call to read.
+//
+// Sizes in the input are not trusted to size a single allocation.
+// Instead, the buffer grows in steps no larger than the bytes read
+// so far, so the memory allocated is proportional to the number of
+// bytes actually read.
+func (d *decoder[P, D]) read(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	d.buf = d.buf[:0]
+	for len(d.buf) < n {
+		m := len(d.buf) + min(n-len(d.buf), max(len(d.buf), minReadStep))
+		if cap(d.buf) < m {
+			buf := make([]byte, len(d.buf), m)
+			copy(buf, d.buf)
+			d.buf = buf
+		}
+		if _, err := io.ReadFull(d.r, d.buf[len(d.buf):m]); err != nil {
+			d.err = unexpectedEOF(err)
+			return nil
+		}
+		d.buf = d.buf[:m]
+	}
+	return d.buf
+}
+
+// UnexpectedEOF returns io.ErrUnexpectedEOF if err is io.EOF,
+// otherwise it returns err.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// A countWriter is an io.Writer that counts the bytes written.
+type countWriter struct {
+	w *bufio.Writer
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// A readByter is an io.Reader that is also an io.ByteReader.
+type readByter interface {
+	io.Reader
+	io.ByteReader
+}
+
+// ByteReader returns r if it implements io.ByteReader,
+// otherwise it returns r wrapped in a bufio.Reader.
+func byteReader(r io.Reader) readByter {
+	if br, ok := r.(readByter); ok {
+		return br
+	}
+	return bufio.NewReader(r)
+}
+
+// A countReader is an io.Reader and io.ByteReader
+// that counts the bytes read.
+type countReader struct {
+	r readByter
+	n int64
+}
+
+func (r *countReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+func (r *countReader) ReadByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err == nil {
+		r.n++
+	}
+	return b, err
+}
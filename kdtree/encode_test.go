@@ -0,0 +1,230 @@
+package kdtree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"runtime"
+	"testing"
+	"testing/quick"
+)
+
+// TestWriteRead tests the Write and Read functions, ensuring that
+// a tree that is written and read back has the same structure and
+// answers InRange queries the same as the original.
+func TestWriteRead(t *testing.T) {
+	runDims(t, testWriteRead[[2]float64], testWriteRead[[3]float64], testWriteRead[[8]float64])
+}
+
+func testWriteRead[P Point](t *testing.T) {
+	if err := quick.Check(func(pts pointSlice[P], upt unitPoint[P], r float64) bool {
+		r = math.Abs(r)
+		tree := New(newNodes(pts))
+
+		var buf bytes.Buffer
+		n, err := Write(&buf, tree, intCodec{})
+		if err != nil || n != int64(buf.Len()) {
+			return false
+		}
+		read, err := Read[P, int](&buf, intCodec{})
+		if err != nil || buf.Len() != 0 {
+			return false
+		}
+
+		if !reflect.DeepEqual(read.shape(nil), tree.shape(nil)) {
+			return false
+		}
+		want := tree.InRange(upt.pt, r, nil, nil)
+		got := read.InRange(upt.pt, r, nil, nil)
+		if len(got) != len(want) {
+			return false
+		}
+		for i := range got {
+			if got[i].Point != want[i].Point || got[i].Data != want[i].Data {
+				return false
+			}
+		}
+		return true
+	}, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestWriteToReadFrom tests the WriteTo and ReadFrom methods.
+func TestWriteToReadFrom(t *testing.T) {
+	type T = Tree[[2]float64, string]
+	tree := New([]*T{
+		{Point: [2]float64{0, 1}, Data: "a"},
+		{Point: [2]float64{1, 0}, Data: "b"},
+		{Point: [2]float64{2, 2}, Data: "c"},
+		{Point: [2]float64{3, 1}, Data: "d"},
+	})
+
+	var buf bytes.Buffer
+	n, err := tree.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("tree.WriteTo(&buf)=_,%v", err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("tree.WriteTo(&buf)=%d,nil, wrote %d bytes", n, buf.Len())
+	}
+	// Trailing bytes must not be consumed.
+	buf.WriteString("tail")
+	wrote := buf.Len()
+
+	var read T
+	n, err = read.ReadFrom(&buf)
+	if err != nil {
+		t.Fatalf("read.ReadFrom(&buf)=_,%v", err)
+	}
+	if n != int64(wrote-buf.Len()) {
+		t.Errorf("read.ReadFrom(&buf)=%d,nil, read %d bytes", n, wrote-buf.Len())
+	}
+	if buf.String() != "tail" {
+		t.Errorf("remaining bytes=%q, want %q", buf.String(), "tail")
+	}
+	if got, want := read.shape(nil), tree.shape(nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("read tree shape=%v, want %v", got, want)
+	}
+
+	var empty *T
+	buf.Reset()
+	if _, err := empty.WriteTo(&buf); err != nil {
+		t.Fatalf("empty.WriteTo(&buf)=_,%v", err)
+	}
+	if _, err := read.ReadFrom(&buf); err == nil {
+		t.Errorf("read.ReadFrom(empty tree) succeeded")
+	}
+}
+
+// TestReadErrors tests that Read reports malformed input.
+func TestReadErrors(t *testing.T) {
+	type T = Tree[[2]float64, int]
+	tree := New([]*T{
+		{Point: [2]float64{0, 1}},
+		{Point: [2]float64{1, 0}},
+		{Point: [2]float64{2, 2}},
+	})
+	var buf bytes.Buffer
+	if _, err := Write(&buf, tree, intCodec{}); err != nil {
+		t.Fatalf("Write(&buf, tree, intCodec{})=_,%v", err)
+	}
+	good := buf.Bytes()
+
+	for i := 0; i < len(good); i++ {
+		_, err := Read[[2]float64, int](bytes.NewReader(good[:i]), intCodec{})
+		if err != io.ErrUnexpectedEOF {
+			t.Errorf("Read(%d of %d bytes)=_,%v, want %v", i, len(good), err, io.ErrUnexpectedEOF)
+		}
+	}
+
+	if _, err := Read[[3]float64, int](bytes.NewReader(good), intCodec{}); !errors.Is(err, ErrFormat) {
+		t.Errorf("Read(2-D tree as 3-D)=_,%v, want %v", err, ErrFormat)
+	}
+
+	bad := append([]byte{}, good...)
+	bad[0] = 'X'
+	if _, err := Read[[2]float64, int](bytes.NewReader(bad), intCodec{}); !errors.Is(err, ErrFormat) {
+		t.Errorf("Read(bad magic)=_,%v, want %v", err, ErrFormat)
+	}
+
+	bad = append([]byte{}, good...)
+	bad[len(magic)] = formatVersion + 1
+	if _, err := Read[[2]float64, int](bytes.NewReader(bad), intCodec{}); !errors.Is(err, ErrFormat) {
+		t.Errorf("Read(bad version)=_,%v, want %v", err, ErrFormat)
+	}
+
+	// A huge node count must not be trusted to size an allocation.
+	huge := []byte("KDT\x00\x01\x02\xff\xff\xff\x7f\x00\x00\x00\x00")
+	if _, err := Read[[2]float64, int](bytes.NewReader(huge), intCodec{}); err != io.ErrUnexpectedEOF {
+		t.Errorf("Read(huge count)=_,%v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	// Nor must a huge data size.
+	huge = append([]byte("KDT\x00\x01\x02\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00"), make([]byte, 16)...)
+	huge = binary.AppendUvarint(huge, math.MaxInt32)
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	before := stats.TotalAlloc
+	_, err := Read[[2]float64, int](bytes.NewReader(huge), intCodec{})
+	runtime.ReadMemStats(&stats)
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("Read(huge data size)=_,%v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	if n := stats.TotalAlloc - before; n > 1<<20 {
+		t.Errorf("Read(huge data size) allocated %d bytes", n)
+	}
+}
+
+// TestReadDeep tests reading a tree that is a single long path,
+// which is read without recursing once per level.
+func TestReadDeep(t *testing.T) {
+	type T = Tree[[1]float64, int]
+	var tree *T
+	const n = 10000
+	for i := 0; i < n; i++ {
+		tree = tree.Insert(&T{Point: [1]float64{float64(i)}, Data: i})
+	}
+	var buf bytes.Buffer
+	if _, err := Write(&buf, tree, intCodec{}); err != nil {
+		t.Fatalf("Write(&buf, tree, intCodec{})=_,%v", err)
+	}
+	read, err := Read[[1]float64, int](&buf, intCodec{})
+	if err != nil {
+		t.Fatalf("Read(&buf, intCodec{})=_,%v", err)
+	}
+	if h := read.Height(); h != n {
+		t.Errorf("read.Height()=%d, want %d", h, n)
+	}
+}
+
+// TestGobCodecCompact tests that GobCodec only writes
+// the gob type information once per tree.
+func TestGobCodecCompact(t *testing.T) {
+	type data struct{ A, B int }
+	type T = Tree[[2]float64, data]
+	const n = 1000
+	var nodes []*T
+	for i := 0; i < n; i++ {
+		nodes = append(nodes, &T{Point: [2]float64{float64(i), float64(-i)}, Data: data{i, -i}})
+	}
+	tree := New(nodes)
+
+	var buf bytes.Buffer
+	if _, err := tree.WriteTo(&buf); err != nil {
+		t.Fatalf("tree.WriteTo(&buf)=_,%v", err)
+	}
+	// Each node is 2 bytes of split and kids, 16 bytes of point,
+	// and a size and gob message for its data, which should be
+	// well under 16 bytes once the type has been described.
+	if max := n * (2 + 16 + 16); buf.Len() > max {
+		t.Errorf("wrote %d bytes, want at most %d", buf.Len(), max)
+	}
+
+	var read T
+	if _, err := read.ReadFrom(&buf); err != nil {
+		t.Fatalf("read.ReadFrom(&buf)=_,%v", err)
+	}
+	if got, want := read.shape(nil), tree.shape(nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("read tree shape=%v, want %v", got, want)
+	}
+}
+
+// An intCodec is a Codec for int Data.
+type intCodec struct{}
+
+func (intCodec) Marshal(d int) ([]byte, error) {
+	return binary.AppendVarint(nil, int64(d)), nil
+}
+
+func (intCodec) Unmarshal(data []byte, d *int) error {
+	x, n := binary.Varint(data)
+	if n != len(data) {
+		return ErrFormat
+	}
+	*d = int(x)
+	return nil
+}
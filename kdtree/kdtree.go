@@ -1,52 +1,74 @@
 // Kdtree is a very simple K-D tree implementation.
-// This implementation uses a fixed value for K.  The intention
-// is to copy the code locally, change K to your needs, and
-// change T.Data's type to suit your needs too.
+// The dimensionality of a tree, K, is given by the length of its
+// point type, and the type of the auxiliary data stored with
+// each point is given by its data type.  For example,
+// Tree[[3]float64, string] is a 3-D tree with string data.
+//
+// Trees of up to 16 dimensions are supported.  Go cannot constrain a
+// type parameter to arrays of any length, so the Point constraint
+// lists each array length, and fixing the dimension in the type keeps
+// points in flat arrays with no per-point allocation or length checks.
+// The limit is deliberate: K-D trees prune poorly in high dimensions,
+// where a linear scan or a different index is usually faster.
 package kdtree
 
 import (
+	"container/heap"
+	"math"
 	"sort"
+	"sync"
 )
 
-// K is the dimensionality of the points in this package's K-D trees.
-const K = 2
+// A Point is a location in K-dimensional space.  Any array
+// of between 1 and 16 float64s is a Point; see the package
+// documentation for why there is a limit.
+type Point interface {
+	~[1]float64 | ~[2]float64 | ~[3]float64 | ~[4]float64 |
+		~[5]float64 | ~[6]float64 | ~[7]float64 | ~[8]float64 |
+		~[9]float64 | ~[10]float64 | ~[11]float64 | ~[12]float64 |
+		~[13]float64 | ~[14]float64 | ~[15]float64 | ~[16]float64
+}
 
-// A Point is a location in K-dimensional space.
-type Point [K]float64
+// Dims returns the dimensionality of points of type P.
+func dims[P Point]() int {
+	var p P
+	return len(p)
+}
 
 // SqDist returns the square distance between two points.
-func (a *Point) sqDist(b *Point) float64 {
+func sqDist[P Point](a, b *P) float64 {
 	sqDist := 0.0
-	for i, x := range a {
-		diff := x - b[i]
+	for i := 0; i < len(*a); i++ {
+		diff := (*a)[i] - (*b)[i]
 		sqDist += diff * diff
 	}
 	return sqDist
 }
 
-// A T is a the node of a K-D tree.  A *T is the root of a K-D tree,
-// and nil is an empty K-D tree.
-type T struct {
+// A Tree is a the node of a K-D tree with points of type P
+// and auxiliary data of type D.  A *Tree is the root of a
+// K-D tree, and nil is an empty K-D tree.
+type Tree[P Point, D any] struct {
 	// Point is the K-dimensional point associated with the
 	// data of this node.
-	Point
+	Point P
 	// Data is auxiliary data associated with the point of this node.
-	Data interface{}
+	Data D
 
 	split       int
-	left, right *T
+	left, right *Tree[P, D]
 }
 
 // Insert returns a new K-D tree with the given node inserted.
 // Inserting a node that is already a member of a K-D tree
 // invalidates that tree.
-func (t *T) Insert(n *T) *T {
+func (t *Tree[P, D]) Insert(n *Tree[P, D]) *Tree[P, D] {
 	return t.insert(0, n)
 }
 
-func (t *T) insert(depth int, n *T) *T {
+func (t *Tree[P, D]) insert(depth int, n *Tree[P, D]) *Tree[P, D] {
 	if t == nil {
-		n.split = depth % K
+		n.split = depth % len(n.Point)
 		n.left, n.right = nil, nil
 		return n
 	}
@@ -58,42 +80,403 @@ func (t *T) insert(depth int, n *T) *T {
 	return t
 }
 
+// Remove returns a new K-D tree with the given node removed.
+// If the node is not a member of the K-D tree then the tree
+// is returned unchanged.
+func (t *Tree[P, D]) Remove(n *Tree[P, D]) *Tree[P, D] {
+	t, _ = t.remove(n)
+	return t
+}
+
+// remove returns the K-D tree with the given node removed and
+// a bool that is true if the node was found in the tree.
+func (t *Tree[P, D]) remove(n *Tree[P, D]) (*Tree[P, D], bool) {
+	if t == nil {
+		return nil, false
+	}
+	if t == n {
+		return t.removeRoot(), true
+	}
+	// Nodes that tie on the splitting dimension may be down
+	// either branch of a tree built with New.
+	var ok bool
+	if n.Point[t.split] <= t.Point[t.split] {
+		if t.left, ok = t.left.remove(n); ok {
+			return t, true
+		}
+	}
+	if n.Point[t.split] >= t.Point[t.split] {
+		t.right, ok = t.right.remove(n)
+	}
+	return t, ok
+}
+
+// RemoveRoot returns the K-D tree with its root node removed.
+// The root is replaced by the minimum node, on the root's splitting
+// dimension, of its right subtree.  If the right subtree is empty then
+// the root is replaced by the minimum node of the left subtree, and
+// the remainder of the left subtree becomes the new right subtree.
+func (t *Tree[P, D]) removeRoot() *Tree[P, D] {
+	var m *Tree[P, D]
+	switch {
+	case t.right != nil:
+		m = t.right.min(t.split)
+		right, _ := t.right.remove(m)
+		m.left, m.right = t.left, right
+	case t.left != nil:
+		m = t.left.min(t.split)
+		right, _ := t.left.remove(m)
+		m.left, m.right = nil, right
+	}
+	if m != nil {
+		m.split = t.split
+	}
+	t.left, t.right = nil, nil
+	return m
+}
+
+// Min returns the node of the K-D tree with the minimum value
+// on the given dimension.
+func (t *Tree[P, D]) min(dim int) *Tree[P, D] {
+	if t == nil {
+		return nil
+	}
+	m := t.left.min(dim)
+	if t.split != dim {
+		if r := t.right.min(dim); m == nil || r != nil && r.Point[dim] < m.Point[dim] {
+			m = r
+		}
+	}
+	if m == nil || t.Point[dim] <= m.Point[dim] {
+		m = t
+	}
+	return m
+}
+
+// Rebalance returns a K-D tree containing the same nodes as the
+// given tree.  If the height of the tree is greater than factor
+// times log2(n+1), the height of a perfectly balanced tree of n nodes,
+// then the returned tree is rebuilt using New.  Otherwise the tree
+// is returned unchanged.
+//
+// Trees built using Insert and Remove can become unbalanced, and
+// Rebalance can be called periodically to restore their balance.
+// A factor of 2 is a reasonable choice.
+func (t *Tree[P, D]) Rebalance(factor float64) *Tree[P, D] {
+	if float64(t.Height()) <= factor*math.Log2(float64(t.count()+1)) {
+		return t
+	}
+	return New(t.appendNodes(nil))
+}
+
+// Count returns the number of nodes in the K-D tree.
+func (t *Tree[P, D]) count() int {
+	if t == nil {
+		return 0
+	}
+	return t.left.count() + 1 + t.right.count()
+}
+
+// AppendNodes appends all nodes of the K-D tree to the given slice.
+func (t *Tree[P, D]) appendNodes(nodes []*Tree[P, D]) []*Tree[P, D] {
+	if t == nil {
+		return nodes
+	}
+	nodes = t.left.appendNodes(nodes)
+	nodes = append(nodes, t)
+	return t.right.appendNodes(nodes)
+}
+
 // InRange appends all nodes in the K-D tree that are within a given
 // distance from the given point to the given slice, which may be nil.
-// To  avoid allocation, the slice can be pre-allocated with a larger
-// capacity and re-used across multiple calls to InRange.
-func (t *T) InRange(pt Point, dist float64, nodes []*T) []*T {
+// Distances are measured using the given Metric, or using Euclidean
+// distance if the Metric is nil.  To  avoid allocation, the slice can
+// be pre-allocated with a larger capacity and re-used across multiple
+// calls to InRange.
+func (t *Tree[P, D]) InRange(pt P, dist float64, m Metric[P], nodes []*Tree[P, D]) []*Tree[P, D] {
 	if dist < 0 {
 		return nodes
 	}
-	return t.inRange(&pt, dist, nodes)
+	return t.inRange(&rangeQuery[P]{pt: &pt, r: dist, m: m}, nodes)
 }
 
-func (t *T) inRange(pt *Point, r float64, nodes []*T) []*T {
+// InBox appends all nodes in the K-D tree that are within the
+// axis-aligned box with the given minimum and maximum corners,
+// inclusive, to the given slice, which may be nil.  As with InRange,
+// the slice can be pre-allocated with a larger capacity and re-used
+// across multiple calls to InBox.
+func (t *Tree[P, D]) InBox(min, max P, nodes []*Tree[P, D]) []*Tree[P, D] {
+	return t.inRange(newRegionQuery[P](Box[P]{Min: min, Max: max}), nodes)
+}
+
+// InRange appends all nodes in the K-D tree that match
+// the query to the given slice.
+func (t *Tree[P, D]) inRange(q query[P], nodes []*Tree[P, D]) []*Tree[P, D] {
+	t.visit(q, func(n *Tree[P, D]) bool {
+		nodes = append(nodes, n)
+		return true
+	})
+	return nodes
+}
+
+// Visit calls f for each node in the K-D tree that is within the given
+// region.  If f returns false then the traversal stops early.
+func (t *Tree[P, D]) Visit(r Region[P], f func(*Tree[P, D]) bool) {
+	t.visit(newRegionQuery(r), f)
+}
+
+// Visit calls f for each node of the K-D tree that matches the query.
+// The return value is false if the traversal was stopped early
+// by f returning false.
+func (t *Tree[P, D]) visit(q query[P], f func(*Tree[P, D]) bool) bool {
 	if t == nil {
+		return true
+	}
+
+	// Near is the side of the splitting plane that is visited
+	// first, and far is the side that is visited second.
+	near, far := t.left, t.right
+	nearOk, farOk, swap := q.split(t.split, t.Point[t.split])
+	if swap {
+		near, far = far, near
+	}
+	if nearOk && !near.visit(q, f) {
+		return false
+	}
+	if nearOk && farOk && q.contains(&t.Point) && !f(t) {
+		return false
+	}
+	if farOk {
+		return far.visit(q, f)
+	}
+	return true
+}
+
+// A query determines the nodes that are visited by a traversal.
+type query[P Point] interface {
+	// Split returns whether the traversal must visit nodes on the
+	// near and far sides of the splitting plane at x on the given
+	// dimension.  The near side is the side with lesser values,
+	// unless swap is true, in which case it is the greater side.
+	// The node on the splitting plane itself is only visited
+	// if both sides are visited.
+	split(dim int, x float64) (near, far, swap bool)
+
+	// Contains returns true if the point matches the query.
+	contains(pt *P) bool
+}
+
+// A rangeQuery matches points that are less than distance r
+// from a point.  Subtrees are pruned on the distance of the
+// point from the splitting plane.
+type rangeQuery[P Point] struct {
+	pt *P
+	r  float64
+	// M is the distance metric.  If m is nil then
+	// Euclidean distance is used.
+	m Metric[P]
+}
+
+func (q *rangeQuery[P]) split(dim int, x float64) (near, far, swap bool) {
+	diff := (*q.pt)[dim] - x
+	var planeDist float64
+	if q.m == nil {
+		planeDist = math.Abs(diff)
+	} else {
+		planeDist = q.m.PlaneDist(dim, diff)
+	}
+	return true, planeDist <= q.r, diff >= 0
+}
+
+func (q *rangeQuery[P]) contains(pt *P) bool {
+	if q.m == nil {
+		return sqDist(pt, q.pt) < q.r*q.r
+	}
+	return q.m.Dist(pt, q.pt) < q.r
+}
+
+// A regionQuery matches points within a Region.  Subtrees are
+// pruned on the Region's bounding box.
+type regionQuery[P Point] struct {
+	r        Region[P]
+	min, max P
+}
+
+func newRegionQuery[P Point](r Region[P]) *regionQuery[P] {
+	q := &regionQuery[P]{r: r}
+	q.min, q.max = r.Bounds()
+	return q
+}
+
+func (q *regionQuery[P]) split(dim int, x float64) (near, far, swap bool) {
+	return q.min[dim] <= x, x <= q.max[dim], false
+}
+
+func (q *regionQuery[P]) contains(pt *P) bool {
+	return q.r.Contains(pt)
+}
+
+// A Region is a subset of K-dimensional space.
+type Region[P Point] interface {
+	// Contains returns true if the point is within the region.
+	Contains(pt *P) bool
+
+	// Bounds returns the minimum and maximum corners of an
+	// axis-aligned box that contains the entire region.
+	Bounds() (min, max P)
+}
+
+// A Ball is the Region of points that are less than
+// Radius distance from Center.
+type Ball[P Point] struct {
+	Center P
+	Radius float64
+}
+
+// Contains implements the Contains method of the Region interface.
+func (b Ball[P]) Contains(pt *P) bool {
+	return sqDist(&b.Center, pt) < b.Radius*b.Radius
+}
+
+// Bounds implements the Bounds method of the Region interface.
+func (b Ball[P]) Bounds() (min, max P) {
+	for i := 0; i < len(min); i++ {
+		min[i] = b.Center[i] - b.Radius
+		max[i] = b.Center[i] + b.Radius
+	}
+	return min, max
+}
+
+// A Box is the Region of points within the axis-aligned
+// box with the corners Min and Max, inclusive.
+type Box[P Point] struct {
+	Min, Max P
+}
+
+// Contains implements the Contains method of the Region interface.
+func (b Box[P]) Contains(pt *P) bool {
+	for i := 0; i < len(*pt); i++ {
+		if x := (*pt)[i]; x < b.Min[i] || x > b.Max[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Bounds implements the Bounds method of the Region interface.
+func (b Box[P]) Bounds() (min, max P) {
+	return b.Min, b.Max
+}
+
+// KNearest appends the k nodes of the K-D tree that are nearest to the
+// given point to the given slice, which may be nil.  The appended nodes
+// are in ascending order of their distance from the point.  If the tree
+// has fewer than k nodes then all of its nodes are appended.  As with
+// InRange, distances are measured using the given Metric, or using
+// Euclidean distance if the Metric is nil, and the slice can be
+// pre-allocated with a larger capacity and re-used across multiple
+// calls to KNearest.
+func (t *Tree[P, D]) KNearest(pt P, k int, m Metric[P], nodes []*Tree[P, D]) []*Tree[P, D] {
+	if k <= 0 {
 		return nodes
 	}
+	h := &nearest[P, D]{pt: &pt, m: m, base: len(nodes), nodes: nodes}
+	t.kNearest(k, h)
 
-	diff := pt[t.split] - t.Point[t.split]
+	// Popping everything from the max-heap leaves the nodes
+	// sorted in ascending order of distance.
+	n := h.Len()
+	for h.Len() > 0 {
+		heap.Pop(h)
+	}
+	return h.nodes[:h.base+n]
+}
+
+func (t *Tree[P, D]) kNearest(k int, h *nearest[P, D]) {
+	if t == nil {
+		return
+	}
+
+	diff := (*h.pt)[t.split] - t.Point[t.split]
 
 	thisSide, otherSide := t.right, t.left
 	if diff < 0 {
 		thisSide, otherSide = t.left, t.right
-		diff = -diff // abs
 	}
-	nodes = thisSide.inRange(pt, r, nodes)
-	if diff <= r {
-		if t.Point.sqDist(pt) < r*r {
-			nodes = append(nodes, t)
-		}
-		nodes = otherSide.inRange(pt, r, nodes)
+	thisSide.kNearest(k, h)
+	if h.Len() < k {
+		heap.Push(h, t)
+	} else if h.pointDist(&t.Point) < h.dist(0) {
+		h.nodes[h.base] = t
+		heap.Fix(h, 0)
+	}
+	if h.Len() < k || h.planeDist(t.split, diff) < h.dist(0) {
+		otherSide.kNearest(k, h)
 	}
+}
 
-	return nodes
+// Nearest is a bounded max-heap of nodes, ordered by their
+// distance from a point.  The heap is stored in nodes[base:],
+// so that KNearest can append to the caller's slice.
+type nearest[P Point, D any] struct {
+	pt *P
+	// M is the distance metric.  If m is nil then distances
+	// are squared Euclidean distances, which order the same
+	// as Euclidean distances but are cheaper to compute.
+	m     Metric[P]
+	base  int
+	nodes []*Tree[P, D]
+}
+
+// PointDist returns the distance of a point from the point.
+func (h *nearest[P, D]) pointDist(pt *P) float64 {
+	if h.m == nil {
+		return sqDist(pt, h.pt)
+	}
+	return h.m.Dist(pt, h.pt)
+}
+
+// PlaneDist returns the lower bound on the distance of any point
+// that differs from the point by diff on the given dimension.
+func (h *nearest[P, D]) planeDist(dim int, diff float64) float64 {
+	if h.m == nil {
+		return diff * diff
+	}
+	return h.m.PlaneDist(dim, diff)
+}
+
+// Dist returns the distance of the ith heap element from the point.
+func (h *nearest[P, D]) dist(i int) float64 {
+	return h.pointDist(&h.nodes[h.base+i].Point)
+}
+
+func (h *nearest[P, D]) Len() int {
+	return len(h.nodes) - h.base
+}
+
+func (h *nearest[P, D]) Less(i, j int) bool {
+	return h.dist(i) > h.dist(j)
+}
+
+func (h *nearest[P, D]) Swap(i, j int) {
+	i, j = h.base+i, h.base+j
+	h.nodes[i], h.nodes[j] = h.nodes[j], h.nodes[i]
+}
+
+func (h *nearest[P, D]) Push(x interface{}) {
+	h.nodes = append(h.nodes, x.(*Tree[P, D]))
+}
+
+// Pop removes the last heap element, but leaves it in place in
+// the underlying array of the slice.
+func (h *nearest[P, D]) Pop() interface{} {
+	n := h.nodes[len(h.nodes)-1]
+	h.nodes = h.nodes[:len(h.nodes)-1]
+	return n
 }
 
 // Height returns the height of the K-D tree.
-func (t *T) Height() int {
+func (t *Tree[P, D]) Height() int {
 	if t == nil {
 		return 0
 	}
@@ -104,19 +487,56 @@ func (t *T) Height() int {
 	return ht + 1
 }
 
+// BuildOptions control the concurrent construction of K-D trees
+// by NewOptions.  The two subtrees of a node are built concurrently
+// if the node is at a depth less than ParallelDepth and the node's subtree
+// has at least ParallelSize nodes.  Likewise, the nodes are pre-sorted on
+// each dimension concurrently if there are at least ParallelSize nodes
+// and ParallelDepth is greater than zero.  A ParallelDepth of zero
+// disables concurrent construction.
+//
+// The tree that is built is the same regardless of these options.
+type BuildOptions struct {
+	ParallelDepth int
+	ParallelSize  int
+}
+
+// DefaultBuildOptions returns the BuildOptions used by New.
+func DefaultBuildOptions() BuildOptions {
+	return BuildOptions{ParallelDepth: 4, ParallelSize: 1 << 14}
+}
+
 // New returns a new K-D tree built using the given nodes.
 // Building a new tree with nodes that are already members of
 // K-D trees invalidates those trees.
-func New(nodes []*T) *T {
+func New[P Point, D any](nodes []*Tree[P, D]) *Tree[P, D] {
+	return NewOptions(nodes, DefaultBuildOptions())
+}
+
+// NewOptions is like New, but the tree is built using the given
+// BuildOptions.
+func NewOptions[P Point, D any](nodes []*Tree[P, D], opts BuildOptions) *Tree[P, D] {
 	if len(nodes) == 0 {
 		return nil
 	}
-	return buildTree(0, preSort(nodes))
+	par := parallel{depth: opts.ParallelDepth, size: opts.ParallelSize}
+	return buildTree(0, preSort(nodes, par), par)
+}
+
+// Parallel holds the concurrent construction settings for a call to NewOptions.
+type parallel struct {
+	depth, size int
+}
+
+// Ok returns true if a subtree at the given depth and with the
+// given number of nodes should be built concurrently.
+func (p parallel) ok(depth, n int) bool {
+	return depth < p.depth && n >= p.size
 }
 
 // BuildTree returns a new tree, built up from the given slice of nodes.
-func buildTree(depth int, nodes *preSorted) *T {
-	split := depth % K
+func buildTree[P Point, D any](depth int, nodes *preSorted[P, D], par parallel) *Tree[P, D] {
+	split := depth % len(nodes.cur)
 	switch nodes.Len() {
 	case 0:
 		return nil
@@ -126,37 +546,66 @@ func buildTree(depth int, nodes *preSorted) *T {
 		nd.left, nd.right = nil, nil
 		return nd
 	}
+	n := nodes.Len()
 	cur, left, right := nodes.splitMed(split)
 	cur.split = split
-	cur.left = buildTree(depth+1, &left)
-	cur.right = buildTree(depth+1, &right)
+	if par.ok(depth, n) {
+		// Left and right use disjoint memory after the split,
+		// so they can be built concurrently.
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			cur.left = buildTree(depth+1, &left, par)
+			wg.Done()
+		}()
+		cur.right = buildTree(depth+1, &right, par)
+		wg.Wait()
+		return cur
+	}
+	cur.left = buildTree(depth+1, &left, par)
+	cur.right = buildTree(depth+1, &right, par)
 	return cur
 }
 
 // PreSorted holds the nodes pre-sorted on each dimension.
-type preSorted struct {
-	// Cur is the currently sorted set of *Ts.
-	cur [K][]*T
+type preSorted[P Point, D any] struct {
+	// Cur is the currently sorted set of *Trees,
+	// with one slice for each dimension.
+	cur [][]*Tree[P, D]
 
 	// Next contains slices that will be used in the results
 	// of splitting a preSorted.
-	next [K][]*T
+	next [][]*Tree[P, D]
 }
 
 // PreSort returns the nodes pre-sorted on each dimension.
-func preSort(nodes []*T) *preSorted {
-	p := new(preSorted)
+func preSort[P Point, D any](nodes []*Tree[P, D], par parallel) *preSorted[P, D] {
+	k := dims[P]()
+	p := &preSorted[P, D]{
+		cur:  make([][]*Tree[P, D], k),
+		next: make([][]*Tree[P, D], k),
+	}
+	var wg sync.WaitGroup
 	for i := range p.cur {
-		p.cur[i] = make([]*T, len(nodes))
-		p.next[i] = make([]*T, len(nodes))
+		p.cur[i] = make([]*Tree[P, D], len(nodes))
+		p.next[i] = make([]*Tree[P, D], len(nodes))
 		copy(p.cur[i], nodes)
-		sort.Sort(&nodeSorter{i, p.cur[i]})
+		if !par.ok(0, len(nodes)) {
+			sort.Sort(&nodeSorter[P, D]{i, p.cur[i]})
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			sort.Sort(&nodeSorter[P, D]{i, p.cur[i]})
+			wg.Done()
+		}(i)
 	}
+	wg.Wait()
 	return p
 }
 
 // Len returns the number of nodes.
-func (p *preSorted) Len() int {
+func (p *preSorted[P, D]) Len() int {
 	return len(p.cur[0])
 }
 
@@ -167,7 +616,7 @@ func (p *preSorted) Len() int {
 //
 // The target of splitMed becomes invalid after the split, as its memory
 // is hijacked by the two returned partitions.
-func (p *preSorted) splitMed(dim int) (med *T, left, right preSorted) {
+func (p *preSorted[P, D]) splitMed(dim int) (med *Tree[P, D], left, right preSorted[P, D]) {
 	m := len(p.cur[dim]) / 2
 	for m > 0 && p.cur[dim][m-1] == p.cur[dim][m] {
 		m--
@@ -175,6 +624,14 @@ func (p *preSorted) splitMed(dim int) (med *T, left, right preSorted) {
 	med = p.cur[dim][m]
 	pivot := med.Point[dim]
 	nleft := leftSize(pivot, dim, p.cur)
+
+	// The per-dimension slice headers of both partitions
+	// share a single allocation.
+	k := len(p.cur)
+	hdrs := make([][]*Tree[P, D], 4*k)
+	left.cur, left.next = hdrs[0:k:k], hdrs[k:2*k:2*k]
+	right.cur, right.next = hdrs[2*k:3*k:3*k], hdrs[3*k:]
+
 	for d := range p.cur {
 		// Use p's next slices as left and right's cur slices.
 		left.cur[d] = p.next[d][:0]
@@ -202,7 +659,7 @@ func (p *preSorted) splitMed(dim int) (med *T, left, right preSorted) {
 	return
 }
 
-func leftSize(pivot float64, d int, nodes [K][]*T) int {
+func leftSize[P Point, D any](pivot float64, d int, nodes [][]*Tree[P, D]) int {
 	var nleft int
 	for _, n := range nodes[d] {
 		if n.Point[d] <= pivot {
@@ -210,24 +667,24 @@ func leftSize(pivot float64, d int, nodes [K][]*T) int {
 		}
 	}
 	// Minus 1 because the median point isn't placed down the left branch.
-	return nleft-1
+	return nleft - 1
 }
 
 // A nodeSorter implements sort.Interface, sortnig the nodes
 // in ascending order of their point values on the split dimension.
-type nodeSorter struct {
+type nodeSorter[P Point, D any] struct {
 	split int
-	nodes []*T
+	nodes []*Tree[P, D]
 }
 
-func (n *nodeSorter) Len() int {
+func (n *nodeSorter[P, D]) Len() int {
 	return len(n.nodes)
 }
 
-func (n *nodeSorter) Swap(i, j int) {
+func (n *nodeSorter[P, D]) Swap(i, j int) {
 	n.nodes[i], n.nodes[j] = n.nodes[j], n.nodes[i]
 }
 
-func (n *nodeSorter) Less(i, j int) bool {
+func (n *nodeSorter[P, D]) Less(i, j int) bool {
 	return n.nodes[i].Point[n.split] < n.nodes[j].Point[n.split]
 }
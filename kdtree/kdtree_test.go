@@ -4,39 +4,71 @@ import (
 	"math"
 	"math/rand"
 	"reflect"
+	"sort"
 	"testing"
 	"testing/quick"
 )
 
+// A unitPoint is a point that implements the quick.Generator
+// interface, generating a random point on the unit hypercube.
+type unitPoint[P Point] struct {
+	pt P
+}
+
+// Generate implements the Generator interface for unitPoints.
+func (unitPoint[P]) Generate(r *rand.Rand, _ int) reflect.Value {
+	return reflect.ValueOf(unitPoint[P]{randPoint[P](r)})
+}
+
 // A pointSlice is a slice of points that implements the quick.Generator
-// interface, generating a random set of points on the unit square.
-type pointSlice []Point
+// interface, generating a random set of points on the unit hypercube.
+type pointSlice[P Point] []P
 
-func (pointSlice) Generate(r *rand.Rand, size int) reflect.Value {
-	ps := make([]Point, size)
+// Generate implements the Generator interface for pointSlices.
+func (pointSlice[P]) Generate(r *rand.Rand, size int) reflect.Value {
+	ps := make(pointSlice[P], size)
 	for i := range ps {
-		for j := range ps[i] {
-			ps[i][j] = r.Float64()
-		}
+		ps[i] = randPoint[P](r)
 	}
 	return reflect.ValueOf(ps)
 }
 
-// Generate implements the Generator interface for Points
-func (p Point) Generate(r *rand.Rand, _ int) reflect.Value {
-	for i := range p {
+// RandPoint returns a random point on the unit hypercube.
+func randPoint[P Point](r *rand.Rand) P {
+	var p P
+	for i := 0; i < len(p); i++ {
 		p[i] = r.Float64()
 	}
-	return reflect.ValueOf(p)
+	return p
+}
+
+// NewNodes returns a slice of new nodes, one for each point.
+func newNodes[P Point](pts []P) []*Tree[P, int] {
+	nodes := make([]*Tree[P, int], len(pts))
+	for i, pt := range pts {
+		nodes[i] = &Tree[P, int]{Point: pt, Data: i}
+	}
+	return nodes
+}
+
+// RunDims runs a test on 2, 3, and 8 dimensional points.
+func runDims(t *testing.T, test2 func(*testing.T), test3 func(*testing.T), test8 func(*testing.T)) {
+	t.Run("2D", test2)
+	t.Run("3D", test3)
+	t.Run("8D", test8)
 }
 
 // TestInsert tests the insert function, ensuring that random points
 // inserted into an empty tree maintain the K-D tree invariant.
 func TestInsert(t *testing.T) {
-	if err := quick.Check(func(pts pointSlice) bool {
-		var tree *T
-		for _, p := range pts {
-			tree = tree.Insert(&T{Point: p})
+	runDims(t, testInsert[[2]float64], testInsert[[3]float64], testInsert[[8]float64])
+}
+
+func testInsert[P Point](t *testing.T) {
+	if err := quick.Check(func(pts pointSlice[P]) bool {
+		var tree *Tree[P, int]
+		for _, n := range newNodes(pts) {
+			tree = tree.Insert(n)
 		}
 		_, ok := tree.invariantHolds()
 		return ok
@@ -45,50 +77,151 @@ func TestInsert(t *testing.T) {
 	}
 }
 
+// TestRemove tests the Remove function, ensuring that after removing
+// random nodes the remaining nodes are all still in the tree and that
+// the K-D tree invariant is maintained.
+func TestRemove(t *testing.T) {
+	runDims(t, testRemove[[2]float64], testRemove[[3]float64], testRemove[[8]float64])
+}
+
+func testRemove[P Point](t *testing.T) {
+	if err := quick.Check(func(pts pointSlice[P], seed int64) bool {
+		r := rand.New(rand.NewSource(seed))
+		nodes := newNodes(pts)
+		tree := New(nodes)
+		// Insert some duplicate points too.
+		for i := 0; i < len(pts)/4; i++ {
+			n := &Tree[P, int]{Point: pts[r.Intn(len(pts))]}
+			nodes = append(nodes, n)
+			tree = tree.Insert(n)
+		}
+
+		r.Shuffle(len(nodes), func(i, j int) {
+			nodes[i], nodes[j] = nodes[j], nodes[i]
+		})
+		for len(nodes) > 0 {
+			n := nodes[len(nodes)-1]
+			nodes = nodes[:len(nodes)-1]
+			tree = tree.Remove(n)
+			if tree.Remove(n) != tree {
+				return false
+			}
+			if _, ok := tree.invariantHolds(); !ok {
+				return false
+			}
+			if !sameNodes(tree.appendNodes(nil), nodes) {
+				return false
+			}
+		}
+		return tree == nil
+	}, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestRebalance tests the Rebalance function, ensuring that
+// a degenerate tree is rebuilt and that a balanced tree is not.
+func TestRebalance(t *testing.T) {
+	runDims(t, testRebalance[[2]float64], testRebalance[[3]float64], testRebalance[[8]float64])
+}
+
+func testRebalance[P Point](t *testing.T) {
+	const n = 100
+	var tree *Tree[P, int]
+	var nodes []*Tree[P, int]
+	for i := 0; i < n; i++ {
+		var p P
+		for j := 0; j < len(p); j++ {
+			p[j] = float64(i)
+		}
+		nd := &Tree[P, int]{Point: p}
+		nodes = append(nodes, nd)
+		tree = tree.Insert(nd)
+	}
+	if h := tree.Height(); h != n {
+		t.Fatalf("tree.Height()=%d, want %d", h, n)
+	}
+
+	tree = tree.Rebalance(2)
+	if h, max := tree.Height(), int(math.Ceil(math.Log2(n+1))); h > max {
+		t.Errorf("rebalanced tree.Height()=%d, want <= %d", h, max)
+	}
+	if _, ok := tree.invariantHolds(); !ok {
+		t.Errorf("rebalanced tree does not hold the K-D tree invariant")
+	}
+	if !sameNodes(tree.appendNodes(nil), nodes) {
+		t.Errorf("rebalanced tree does not contain the original nodes")
+	}
+
+	if r := tree.Rebalance(2); r != tree {
+		t.Errorf("balanced tree was rebuilt")
+	}
+}
+
+// SameNodes returns true if the two slices contain the same nodes,
+// ignoring order.
+func sameNodes[P Point, D any](a, b []*Tree[P, D]) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	in := make(map[*Tree[P, D]]int, len(a))
+	for _, n := range a {
+		in[n]++
+	}
+	for _, n := range b {
+		if in[n] == 0 {
+			return false
+		}
+		in[n]--
+	}
+	return true
+}
+
 // When splitting on the median, values after the median index may tie the median.
 // Make sure that we correctly account for this when splitting and reusing slices.
 // The test ensures that we don't panic.
 // See issue 18.
 func TestMedianTies(t *testing.T) {
-	 New([]*T{
-		&T{Point: Point{0, 1}},
-		&T{Point: Point{0, 1}},
-		&T{Point: Point{0, 0}},
-		&T{Point: Point{1, 0}},
+	type T = Tree[[2]float64, int]
+	New([]*T{
+		&T{Point: [2]float64{0, 1}},
+		&T{Point: [2]float64{0, 1}},
+		&T{Point: [2]float64{0, 0}},
+		&T{Point: [2]float64{1, 0}},
 	})
 
 	// This is the data that originally produced issue 18.
-	 New([]*T{
-		&T{Point: Point{6, 9}},
-		&T{Point: Point{6, 4}},
-		&T{Point: Point{4, 6}},
-		&T{Point: Point{0, 1}},
-		&T{Point: Point{0, 3}},
-		&T{Point: Point{5, 8}},
-		&T{Point: Point{2, 3}},
-		&T{Point: Point{3, 4}},
-		&T{Point: Point{2, 2}},
-		&T{Point: Point{6, 2}},
-		&T{Point: Point{2, 3}},
-		&T{Point: Point{5, 8}},
-		&T{Point: Point{2, 2}},
-		&T{Point: Point{7, 2}},
-		&T{Point: Point{8, 6}},
-		&T{Point: Point{5, 0}},
-		&T{Point: Point{1, 6}},
-		&T{Point: Point{9, 0}},
+	New([]*T{
+		&T{Point: [2]float64{6, 9}},
+		&T{Point: [2]float64{6, 4}},
+		&T{Point: [2]float64{4, 6}},
+		&T{Point: [2]float64{0, 1}},
+		&T{Point: [2]float64{0, 3}},
+		&T{Point: [2]float64{5, 8}},
+		&T{Point: [2]float64{2, 3}},
+		&T{Point: [2]float64{3, 4}},
+		&T{Point: [2]float64{2, 2}},
+		&T{Point: [2]float64{6, 2}},
+		&T{Point: [2]float64{2, 3}},
+		&T{Point: [2]float64{5, 8}},
+		&T{Point: [2]float64{2, 2}},
+		&T{Point: [2]float64{7, 2}},
+		&T{Point: [2]float64{8, 6}},
+		&T{Point: [2]float64{5, 0}},
+		&T{Point: [2]float64{1, 6}},
+		&T{Point: [2]float64{9, 0}},
 	})
 }
 
 // TestMake tests the Make function, ensuring that a tree built
 // using random points respects the K-D tree invariant.
 func TestMake(t *testing.T) {
-	if err := quick.Check(func(pts pointSlice) bool {
-		nodes := make([]*T, len(pts))
-		for i, pt := range pts {
-			nodes[i] = &T{Point: pt}
-		}
-		tree := New(nodes)
+	runDims(t, testMake[[2]float64], testMake[[3]float64], testMake[[8]float64])
+}
+
+func testMake[P Point](t *testing.T) {
+	if err := quick.Check(func(pts pointSlice[P]) bool {
+		tree := New(newNodes(pts))
 		_, ok := tree.invariantHolds()
 		return ok
 	}, nil); err != nil {
@@ -96,26 +229,58 @@ func TestMake(t *testing.T) {
 	}
 }
 
+// TestParallelMake tests that the NewOptions function builds the same
+// tree when building concurrently as it does when building serially.
+func TestParallelMake(t *testing.T) {
+	runDims(t, testParallelMake[[2]float64], testParallelMake[[3]float64], testParallelMake[[8]float64])
+}
+
+func testParallelMake[P Point](t *testing.T) {
+	if err := quick.Check(func(pts pointSlice[P]) bool {
+		nodes := newNodes(pts)
+
+		want := NewOptions(nodes, BuildOptions{}).shape(nil)
+		got := NewOptions(nodes, BuildOptions{ParallelDepth: 3, ParallelSize: 4}).shape(nil)
+
+		return reflect.DeepEqual(got, want)
+	}, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// Shape appends a pre-order description of the K-D tree to the given
+// slice: the Data and split dimension of each node, with -1 for nil.
+func (t *Tree[P, D]) shape(s []any) []any {
+	if t == nil {
+		return append(s, -1)
+	}
+	s = append(s, t.Data, t.split)
+	s = t.left.shape(s)
+	return t.right.shape(s)
+}
+
 // TestInRange tests the InRange function, ensuring that all points
 // in the range are reported, and all points reported are indeed in
 // the range.
 func TestInRange(t *testing.T) {
-	if err := quick.Check(func(pts pointSlice, pt Point, r float64) bool {
+	runDims(t, testInRange[[2]float64], testInRange[[3]float64], testInRange[[8]float64])
+}
+
+func testInRange[P Point](t *testing.T) {
+	if err := quick.Check(func(pts pointSlice[P], upt unitPoint[P], r float64) bool {
+		pt := upt.pt
 		r = math.Abs(r)
-		nodes := make([]*T, len(pts))
-		for i, pt := range pts {
-			nodes[i] = &T{Point: pt}
-		}
+		nodes := newNodes(pts)
 
 		tree := New(nodes)
-		in := make(map[*T]bool, len(nodes))
-		for _, n := range tree.InRange(pt, r, nil) {
+		in := make(map[*Tree[P, int]]bool, len(nodes))
+		for _, n := range tree.InRange(pt, r, nil, nil) {
 			in[n] = true
 		}
 
 		num := 0
 		for _, n := range nodes {
-			if pt.sqDist(&n.Point) <= r*r {
+			if sqDist(&pt, &n.Point) <= r*r {
 				num++
 				if !in[n] {
 					return false
@@ -128,15 +293,137 @@ func TestInRange(t *testing.T) {
 	}
 }
 
+// TestInBox tests the InBox function, ensuring that all points
+// in the box are reported, and all points reported are indeed in
+// the box.
+func TestInBox(t *testing.T) {
+	runDims(t, testInBox[[2]float64], testInBox[[3]float64], testInBox[[8]float64])
+}
+
+func testInBox[P Point](t *testing.T) {
+	if err := quick.Check(func(pts pointSlice[P], a, b unitPoint[P]) bool {
+		min, max := a.pt, b.pt
+		for i := 0; i < len(min); i++ {
+			if min[i] > max[i] {
+				min[i], max[i] = max[i], min[i]
+			}
+		}
+		nodes := newNodes(pts)
+
+		tree := New(nodes)
+		in := make(map[*Tree[P, int]]bool, len(nodes))
+		for _, n := range tree.InBox(min, max, nil) {
+			in[n] = true
+		}
+
+		num := 0
+	nodes:
+		for _, n := range nodes {
+			for i := 0; i < len(min); i++ {
+				if n.Point[i] < min[i] || n.Point[i] > max[i] {
+					continue nodes
+				}
+			}
+			num++
+			if !in[n] {
+				return false
+			}
+		}
+		return num == len(in)
+	}, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestVisit tests the Visit function, ensuring that it visits
+// the same nodes as InRange, and that it stops early when
+// its function returns false.
+func TestVisit(t *testing.T) {
+	runDims(t, testVisit[[2]float64], testVisit[[3]float64], testVisit[[8]float64])
+}
+
+func testVisit[P Point](t *testing.T) {
+	if err := quick.Check(func(pts pointSlice[P], upt unitPoint[P], r float64, stop uint8) bool {
+		r = math.Abs(r)
+		tree := New(newNodes(pts))
+		want := tree.InRange(upt.pt, r, nil, nil)
+
+		var got []*Tree[P, int]
+		tree.Visit(Ball[P]{Center: upt.pt, Radius: r}, func(n *Tree[P, int]) bool {
+			got = append(got, n)
+			return true
+		})
+		if !sameNodes(got, want) {
+			return false
+		}
+
+		n := 0
+		tree.Visit(Ball[P]{Center: upt.pt, Radius: r}, func(*Tree[P, int]) bool {
+			n++
+			return n < int(stop)
+		})
+		// Visiting stops after the first call for which n >= stop.
+		wantN := int(stop)
+		if wantN == 0 {
+			wantN = 1
+		}
+		if wantN > len(want) {
+			wantN = len(want)
+		}
+		return n == wantN
+	}, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestKNearest tests the KNearest function, ensuring that the
+// reported nodes are the k nearest, in ascending order of distance.
+func TestKNearest(t *testing.T) {
+	runDims(t, testKNearest[[2]float64], testKNearest[[3]float64], testKNearest[[8]float64])
+}
+
+func testKNearest[P Point](t *testing.T) {
+	if err := quick.Check(func(pts pointSlice[P], upt unitPoint[P], k uint8) bool {
+		pt := upt.pt
+		nodes := newNodes(pts)
+
+		tree := New(nodes)
+		prefix := []*Tree[P, int]{nil}
+		near := tree.KNearest(pt, int(k), nil, prefix)
+		if near[0] != nil {
+			return false
+		}
+		near = near[1:]
+
+		sort.Slice(nodes, func(i, j int) bool {
+			return sqDist(&pt, &nodes[i].Point) < sqDist(&pt, &nodes[j].Point)
+		})
+		if int(k) < len(nodes) {
+			nodes = nodes[:k]
+		}
+		if len(near) != len(nodes) {
+			return false
+		}
+		for i, n := range near {
+			if sqDist(&pt, &n.Point) != sqDist(&pt, &nodes[i].Point) {
+				return false
+			}
+		}
+		return true
+	}, nil); err != nil {
+		t.Error(err)
+	}
+}
+
 // InvariantHolds returns the points in this subtree, and a bool
 // that is true if the K-D tree invariant holds.  The K-D tree invariant
 // states that all points in the left subtree have values less than that
 // of the current node on the splitting dimension, and the points
 // in the right subtree have values greater than or equal to that of
 // the current node.
-func (t *T) invariantHolds() ([]Point, bool) {
+func (t *Tree[P, D]) invariantHolds() ([]P, bool) {
 	if t == nil {
-		return []Point{}, true
+		return []P{}, true
 	}
 
 	left, leftOk := t.left.invariantHolds()
@@ -164,13 +451,17 @@ func (t *T) invariantHolds() ([]Point, bool) {
 }
 
 func TestPreSort(t *testing.T) {
-	if err := quick.Check(func(pts pointSlice) bool {
-		nodes := make([]*T, len(pts))
-		for i, pt := range pts {
-			nodes[i] = &T{Point: pt}
-		}
+	runDims(t, testPreSort[[2]float64], testPreSort[[3]float64], testPreSort[[8]float64])
+}
+
+func testPreSort[P Point](t *testing.T) {
+	if err := quick.Check(func(pts pointSlice[P]) bool {
+		nodes := newNodes(pts)
 
-		p := preSort(nodes)
+		p := preSort(nodes, parallel{})
+		if len(p.cur) != dims[P]() {
+			return false
+		}
 		for i := range p.cur {
 			if !isSortedOnDim(i, p.cur[i]) || len(p.cur[i]) != len(nodes) {
 				return false
@@ -183,24 +474,23 @@ func TestPreSort(t *testing.T) {
 }
 
 func TestPreSort_SplitMed(t *testing.T) {
-	if err := quick.Check(func(pts pointSlice, dim int) bool {
+	runDims(t, testPreSortSplitMed[[2]float64], testPreSortSplitMed[[3]float64], testPreSortSplitMed[[8]float64])
+}
+
+func testPreSortSplitMed[P Point](t *testing.T) {
+	if err := quick.Check(func(pts pointSlice[P], dim int) bool {
 		if len(pts) == 0 {
 			return true
 		}
 		if dim < 0 {
 			dim = -dim
 		}
-		dim %= K
-
-		nodes := make([]*T, len(pts))
-		for i, pt := range pts {
-			nodes[i] = &T{Point: pt}
-		}
+		dim %= dims[P]()
 
-		sorted := preSort(nodes)
+		sorted := preSort(newNodes(pts), parallel{})
 		med, left, right := sorted.splitMed(dim)
 
-		for i, p := range [2]*preSorted{&left, &right} {
+		for i, p := range [2]*preSorted[P, int]{&left, &right} {
 			for d, ns := range p.cur {
 				if len(ns) != p.Len() {
 					return false
@@ -226,7 +516,7 @@ func TestPreSort_SplitMed(t *testing.T) {
 
 // IsSortedOnDim returns true if the given slice is in sorted order
 // on the given dimension.
-func isSortedOnDim(dim int, nodes []*T) bool {
+func isSortedOnDim[P Point, D any](dim int, nodes []*Tree[P, D]) bool {
 	if len(nodes) == 0 {
 		return true
 	}
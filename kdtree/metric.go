@@ -0,0 +1,89 @@
+package kdtree
+
+import "math"
+
+// A Metric measures the distance between points.
+type Metric[P Point] interface {
+	// Dist returns the distance between two points.
+	Dist(a, b *P) float64
+
+	// PlaneDist returns a lower bound on the distance between
+	// two points that differ by diff on the given dimension.
+	// It is used to prune subtrees on the far side of a
+	// splitting plane, so it must never be greater than the
+	// distance between any two such points.
+	PlaneDist(dim int, diff float64) float64
+}
+
+// Euclidean is the Euclidean, or L2, distance Metric.
+// It is the Metric used by InRange and KNearest when
+// they are given a nil Metric.
+type Euclidean[P Point] struct{}
+
+// Dist implements the Dist method of the Metric interface.
+func (Euclidean[P]) Dist(a, b *P) float64 {
+	return math.Sqrt(sqDist(a, b))
+}
+
+// PlaneDist implements the PlaneDist method of the Metric interface.
+func (Euclidean[P]) PlaneDist(_ int, diff float64) float64 {
+	return math.Abs(diff)
+}
+
+// Manhattan is the Manhattan, or L1, distance Metric:
+// the sum of the absolute differences on each dimension.
+type Manhattan[P Point] struct{}
+
+// Dist implements the Dist method of the Metric interface.
+func (Manhattan[P]) Dist(a, b *P) float64 {
+	d := 0.0
+	for i := 0; i < len(*a); i++ {
+		d += math.Abs((*a)[i] - (*b)[i])
+	}
+	return d
+}
+
+// PlaneDist implements the PlaneDist method of the Metric interface.
+func (Manhattan[P]) PlaneDist(_ int, diff float64) float64 {
+	return math.Abs(diff)
+}
+
+// Chebyshev is the Chebyshev, or L∞, distance Metric:
+// the maximum absolute difference on any dimension.
+type Chebyshev[P Point] struct{}
+
+// Dist implements the Dist method of the Metric interface.
+func (Chebyshev[P]) Dist(a, b *P) float64 {
+	d := 0.0
+	for i := 0; i < len(*a); i++ {
+		d = math.Max(d, math.Abs((*a)[i]-(*b)[i]))
+	}
+	return d
+}
+
+// PlaneDist implements the PlaneDist method of the Metric interface.
+func (Chebyshev[P]) PlaneDist(_ int, diff float64) float64 {
+	return math.Abs(diff)
+}
+
+// WeightedEuclidean is a Euclidean distance Metric in which the
+// squared difference on each dimension is scaled by a weight.
+// The weights must be non-negative.
+type WeightedEuclidean[P Point] struct {
+	Weights P
+}
+
+// Dist implements the Dist method of the Metric interface.
+func (w WeightedEuclidean[P]) Dist(a, b *P) float64 {
+	d := 0.0
+	for i := 0; i < len(*a); i++ {
+		diff := (*a)[i] - (*b)[i]
+		d += w.Weights[i] * diff * diff
+	}
+	return math.Sqrt(d)
+}
+
+// PlaneDist implements the PlaneDist method of the Metric interface.
+func (w WeightedEuclidean[P]) PlaneDist(dim int, diff float64) float64 {
+	return math.Sqrt(w.Weights[dim]) * math.Abs(diff)
+}
@@ -0,0 +1,111 @@
+package kdtree
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+	"testing/quick"
+)
+
+// Metrics returns the Metrics to test, with random weights
+// for the weighted Euclidean metric.
+func metrics[P Point](r *rand.Rand) map[string]Metric[P] {
+	return map[string]Metric[P]{
+		"Euclidean":         Euclidean[P]{},
+		"Manhattan":         Manhattan[P]{},
+		"Chebyshev":         Chebyshev[P]{},
+		"WeightedEuclidean": WeightedEuclidean[P]{Weights: randPoint[P](r)},
+	}
+}
+
+// TestInRangeMetric tests the InRange function with each Metric,
+// ensuring that all points in the range are reported, and all points
+// reported are indeed in the range.
+func TestInRangeMetric(t *testing.T) {
+	runDims(t, testInRangeMetric[[2]float64], testInRangeMetric[[3]float64], testInRangeMetric[[8]float64])
+}
+
+func testInRangeMetric[P Point](t *testing.T) {
+	for name, m := range metrics[P](rand.New(rand.NewSource(0))) {
+		if err := quick.Check(func(pts pointSlice[P], upt unitPoint[P], r float64) bool {
+			pt := upt.pt
+			r = math.Abs(r)
+			nodes := newNodes(pts)
+
+			tree := New(nodes)
+			in := make(map[*Tree[P, int]]bool, len(nodes))
+			for _, n := range tree.InRange(pt, r, m, nil) {
+				in[n] = true
+			}
+
+			num := 0
+			for _, n := range nodes {
+				if m.Dist(&pt, &n.Point) < r {
+					num++
+					if !in[n] {
+						return false
+					}
+				}
+			}
+			return num == len(in)
+		}, nil); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+// TestKNearestMetric tests the KNearest function with each Metric,
+// ensuring that the reported nodes are the k nearest, in ascending
+// order of distance.
+func TestKNearestMetric(t *testing.T) {
+	runDims(t, testKNearestMetric[[2]float64], testKNearestMetric[[3]float64], testKNearestMetric[[8]float64])
+}
+
+func testKNearestMetric[P Point](t *testing.T) {
+	for name, m := range metrics[P](rand.New(rand.NewSource(0))) {
+		if err := quick.Check(func(pts pointSlice[P], upt unitPoint[P], k uint8) bool {
+			pt := upt.pt
+			nodes := newNodes(pts)
+
+			tree := New(nodes)
+			near := tree.KNearest(pt, int(k), m, nil)
+
+			sort.Slice(nodes, func(i, j int) bool {
+				return m.Dist(&pt, &nodes[i].Point) < m.Dist(&pt, &nodes[j].Point)
+			})
+			if int(k) < len(nodes) {
+				nodes = nodes[:k]
+			}
+			if len(near) != len(nodes) {
+				return false
+			}
+			for i, n := range near {
+				if m.Dist(&pt, &n.Point) != m.Dist(&pt, &nodes[i].Point) {
+					return false
+				}
+			}
+			return true
+		}, nil); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+// TestPlaneDist tests that each Metric's PlaneDist is a lower
+// bound on the distance between points differing on a dimension.
+func TestPlaneDist(t *testing.T) {
+	runDims(t, testPlaneDist[[2]float64], testPlaneDist[[3]float64], testPlaneDist[[8]float64])
+}
+
+func testPlaneDist[P Point](t *testing.T) {
+	for name, m := range metrics[P](rand.New(rand.NewSource(0))) {
+		if err := quick.Check(func(a, b unitPoint[P], dim uint8) bool {
+			d := int(dim) % len(a.pt)
+			// Allow for floating point rounding.
+			return m.PlaneDist(d, a.pt[d]-b.pt[d]) <= m.Dist(&a.pt, &b.pt)*(1+1e-12)
+		}, nil); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
@@ -5,6 +5,7 @@
 package rbtree
 
 import (
+	"cmp"
 	"math/rand"
 	"testing"
 	"time"
@@ -20,6 +21,10 @@ func randomInts(b *testing.B) []int {
 	return ints
 }
 
+// The benchmarks without a suffix use an RbTree, with Key keys and
+// interface{} values.  The Ordered benchmarks are the same, but use
+// a Tree[int, int].
+
 func BenchmarkAdd(b *testing.B) {
 	b.StopTimer()
 	tree := New()
@@ -30,6 +35,42 @@ func BenchmarkAdd(b *testing.B) {
 	}
 }
 
+func BenchmarkAddOrdered(b *testing.B) {
+	b.StopTimer()
+	tree := NewOrdered[int, int]()
+	ints := randomInts(b)
+	b.StartTimer()
+	for i := 0; i < len(ints); i += 1 {
+		tree.Add(ints[i], ints[i])
+	}
+}
+
+func BenchmarkFind(b *testing.B) {
+	b.StopTimer()
+	tree := New()
+	ints := randomInts(b)
+	for i := 0; i < len(ints); i += 1 {
+		tree.Add(intKey(ints[i]), ints[i])
+	}
+	b.StartTimer()
+	for i := 0; i < len(ints); i += 1 {
+		tree.Find(intKey(ints[i]))
+	}
+}
+
+func BenchmarkFindOrdered(b *testing.B) {
+	b.StopTimer()
+	tree := NewOrdered[int, int]()
+	ints := randomInts(b)
+	for i := 0; i < len(ints); i += 1 {
+		tree.Add(ints[i], ints[i])
+	}
+	b.StartTimer()
+	for i := 0; i < len(ints); i += 1 {
+		tree.Find(ints[i])
+	}
+}
+
 func BenchmarkRemove(b *testing.B) {
 	b.StopTimer()
 	tree := New()
@@ -43,6 +84,19 @@ func BenchmarkRemove(b *testing.B) {
 	}
 }
 
+func BenchmarkRemoveOrdered(b *testing.B) {
+	b.StopTimer()
+	tree := NewOrdered[int, int]()
+	ints := randomInts(b)
+	for i := 0; i < len(ints); i += 1 {
+		tree.Add(ints[i], ints[i])
+	}
+	b.StartTimer()
+	for i := 0; i < len(ints); i += 1 {
+		tree.Remove(ints[i])
+	}
+}
+
 func BenchmarkRemoveNode(b *testing.B) {
 	b.StopTimer()
 	tree := New()
@@ -57,6 +111,20 @@ func BenchmarkRemoveNode(b *testing.B) {
 	}
 }
 
+func BenchmarkRemoveNodeOrdered(b *testing.B) {
+	b.StopTimer()
+	tree := NewOrdered[int, int]()
+	nodes := make([]*TreeNode[int, int], b.N)
+	for i := 0; i < len(nodes); i += 1 {
+		k := rand.Int()
+		nodes[i] = tree.Add(k, k)
+	}
+	b.StartTimer()
+	for i := 0; i < len(nodes); i += 1 {
+		tree.RemoveNode(nodes[i])
+	}
+}
+
 // The following tests match the benchmarks in GoLLRB for comparison.
 
 func BenchmarkInsert(b *testing.B) {
@@ -66,6 +134,13 @@ func BenchmarkInsert(b *testing.B) {
 	}
 }
 
+func BenchmarkInsertOrdered(b *testing.B) {
+	tree := NewOrdered[int, int]()
+	for i := 0; i < b.N; i++ {
+		tree.Replace(b.N-i, b.N-i)
+	}
+}
+
 func BenchmarkDelete(b *testing.B) {
 	b.StopTimer()
 	tree := New()
@@ -77,3 +152,55 @@ func BenchmarkDelete(b *testing.B) {
 		tree.Remove(intKey(i))
 	}
 }
+
+func BenchmarkDeleteOrdered(b *testing.B) {
+	b.StopTimer()
+	tree := NewOrdered[int, int]()
+	for i := 0; i < b.N; i++ {
+		tree.Replace(b.N-i, b.N-i)
+	}
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		tree.Remove(i)
+	}
+}
+
+func BenchmarkAddPersistent(b *testing.B) {
+	b.StopTimer()
+	tree := NewPersistentOrdered[int, int]()
+	ints := randomInts(b)
+	b.StartTimer()
+	for i := 0; i < len(ints); i += 1 {
+		tree = tree.Add(ints[i], ints[i])
+	}
+}
+
+func BenchmarkRemovePersistent(b *testing.B) {
+	b.StopTimer()
+	tree := NewPersistentOrdered[int, int]()
+	ints := randomInts(b)
+	for i := 0; i < len(ints); i += 1 {
+		tree = tree.Add(ints[i], ints[i])
+	}
+	b.StartTimer()
+	for i := 0; i < len(ints); i += 1 {
+		tree = tree.Remove(ints[i])
+	}
+}
+
+func BenchmarkAddSorted(b *testing.B) {
+	tree := NewOrdered[int, int]()
+	for i := 0; i < b.N; i++ {
+		tree.Add(i, i)
+	}
+}
+
+func BenchmarkBuildSorted(b *testing.B) {
+	b.StopTimer()
+	ints := make([]int, b.N)
+	for i := range ints {
+		ints[i] = i
+	}
+	b.StartTimer()
+	BuildSorted(cmp.Compare[int], ints, ints)
+}
@@ -0,0 +1,144 @@
+// Copyright 2011 Ethan Burns
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rbtree
+
+import "math/bits"
+
+// BuildSorted returns a new tree that orders its keys using the given
+// comparison function, as with NewFunc, and that binds each of the
+// keys to the value at the same index.  The keys must be sorted in
+// ascending order, but they needn't be unique.  BuildSorted panics if
+// the keys are not sorted or if there are not as many values as keys.
+// This operation is O(n) in the number of keys.
+func BuildSorted[K, V any](cmp func(a, b K) int, keys []K, values []V) *Tree[K, V] {
+	if len(keys) != len(values) {
+		panic("rbtree: BuildSorted with different numbers of keys and values")
+	}
+	for i := 1; i < len(keys); i++ {
+		if cmp(keys[i-1], keys[i]) > 0 {
+			panic("rbtree: BuildSorted keys are not sorted")
+		}
+	}
+	t := NewFunc[K, V](cmp)
+	t.build(keys, values)
+	return t
+}
+
+// build makes t a tree of the sorted keys and values.
+//
+// Each subtree is rooted at its middle key, so the sizes of the
+// left and right subtrees differ by at most one, and every leaf is
+// at depth d or d+1, where d = ⌊lg(n+1)⌋.  The Nodes at depth d,
+// which are only on the bottom level of the tree, are red and all
+// others are black; every path from the root to a leaf has d black
+// Nodes.
+func (t *Tree[K, V]) build(keys []K, values []V) {
+	t.root = t.buildSubtree(keys, values, 0, bits.Len(uint(len(keys)+1))-1)
+	t.root.parent = t.nilNode
+	t.len = len(keys)
+}
+
+func (t *Tree[K, V]) buildSubtree(keys []K, values []V, depth, redDepth int) *TreeNode[K, V] {
+	if len(keys) == 0 {
+		return t.nilNode
+	}
+	m := len(keys) / 2
+	n := t.newNode(keys[m], values[m])
+	n.size = len(keys)
+	n.color = black
+	if depth == redDepth {
+		n.color = red
+	}
+	n.left = t.buildSubtree(keys[:m], values[:m], depth+1, redDepth)
+	if n.left != t.nilNode {
+		n.left.parent = n
+	}
+	n.right = t.buildSubtree(keys[m+1:], values[m+1:], depth+1, redDepth)
+	if n.right != t.nilNode {
+		n.right.parent = n
+	}
+	if t.update != nil {
+		t.update(n)
+	}
+	return n
+}
+
+// Union returns a new tree with the bindings of both trees.  The
+// trees must order their keys the same way.  The trees are treated as
+// multisets: a key bound i times in t and j times in u is bound
+// max(i, j) times in the union, and t's bindings come first.  This
+// operation is O(n+m) in the number of Nodes in the two trees.
+func (t *Tree[K, V]) Union(u *Tree[K, V]) *Tree[K, V] {
+	return t.merge(u, true, true, true)
+}
+
+// Intersect returns a new tree with the bindings of t for the keys
+// that are in both trees.  The trees must order their keys the same
+// way.  The trees are treated as multisets: a key bound i times in t
+// and j times in u is bound min(i, j) times in the intersection.
+// This operation is O(n+m) in the number of Nodes in the two trees.
+func (t *Tree[K, V]) Intersect(u *Tree[K, V]) *Tree[K, V] {
+	return t.merge(u, false, true, false)
+}
+
+// Difference returns a new tree with the bindings of t for the keys
+// that are not in u.  The trees must order their keys the same way.
+// The trees are treated as multisets: a key bound i times in t and j
+// times in u is bound max(0, i-j) times in the difference.  This
+// operation is O(n+m) in the number of Nodes in the two trees.
+func (t *Tree[K, V]) Difference(u *Tree[K, V]) *Tree[K, V] {
+	return t.merge(u, true, false, false)
+}
+
+// merge returns a new tree built by merging the in-order sequences
+// of the two trees.  The booleans select whether bindings with keys
+// that are only in t, in both trees, or only in u are kept.  For keys
+// in both trees, the binding from t is kept.
+func (t *Tree[K, V]) merge(u *Tree[K, V], onlyT, both, onlyU bool) *Tree[K, V] {
+	var keys []K
+	var values []V
+	keep := func(n *TreeNode[K, V]) {
+		keys = append(keys, n.Key)
+		values = append(values, n.Value)
+	}
+	a, b := first(t), first(u)
+	for a != nil && b != nil {
+		switch c := t.cmp(a.Key, b.Key); {
+		case c < 0:
+			if onlyT {
+				keep(a)
+			}
+			a = a.Next()
+		case c > 0:
+			if onlyU {
+				keep(b)
+			}
+			b = b.Next()
+		default:
+			if both {
+				keep(a)
+			}
+			a, b = a.Next(), b.Next()
+		}
+	}
+	for ; a != nil && onlyT; a = a.Next() {
+		keep(a)
+	}
+	for ; b != nil && onlyU; b = b.Next() {
+		keep(b)
+	}
+	m := NewFunc[K, V](t.cmp)
+	m.build(keys, values)
+	return m
+}
+
+// first returns the Node with the minimum key in the tree,
+// or nil if the tree is empty.
+func first[K, V any](t *Tree[K, V]) *TreeNode[K, V] {
+	if t.root == t.nilNode {
+		return nil
+	}
+	return treeMinimum(t.root)
+}
@@ -0,0 +1,132 @@
+// Copyright 2011 Ethan Burns
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rbtree
+
+import (
+	"cmp"
+	"math/rand"
+	"testing"
+)
+
+func TestBuildSorted(t *testing.T) {
+	for n := 0; n < 300; n++ {
+		ks := make([]int, n)
+		vs := make([]string, n)
+		for i := range ks {
+			ks[i] = i / 2
+			vs[i] = string(rune('a' + i%26))
+		}
+		tree := BuildSorted(cmp.Compare[int], ks, vs)
+		ensureInvariants(t, tree)
+		if tree.Len() != n || countNodes(tree.root) != n {
+			t.Fatalf("Tree of %d keys has Len %d and %d nodes", n, tree.Len(), countNodes(tree.root))
+		}
+		i := 0
+		tree.Do(func(k int, v string) {
+			if k != ks[i] || v != vs[i] {
+				t.Fatalf("Binding %d is %d, %s, expected %d, %s", i, k, v, ks[i], vs[i])
+			}
+			i++
+		})
+		// The tree must remain valid when it is modified.
+		tree.Add(n/4, "")
+		tree.Remove(n / 3)
+		ensureInvariants(t, tree)
+	}
+}
+
+func TestBuildSortedPanics(t *testing.T) {
+	for _, test := range []struct {
+		keys   []int
+		values []int
+	}{
+		{[]int{1, 2}, []int{1}},
+		{[]int{2, 1}, []int{1, 2}},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("BuildSorted(%v, %v) did not panic", test.keys, test.values)
+				}
+			}()
+			BuildSorted(cmp.Compare[int], test.keys, test.values)
+		}()
+	}
+}
+
+// randMultiset returns a tree with random keys in [0, n),
+// some bound more than once, and the count of each key.
+func randMultiset(rng *rand.Rand, n int) (*Tree[int, int], map[int]int) {
+	tree := NewOrdered[int, int]()
+	counts := make(map[int]int)
+	for i := 0; i < n; i++ {
+		k := rng.Intn(n)
+		tree.Add(k, i)
+		counts[k]++
+	}
+	return tree, counts
+}
+
+func TestSetOperations(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	for _, sizes := range [][2]int{{0, 0}, {0, 10}, {10, 0}, {100, 100}, {50, 200}, {1000, 300}} {
+		a, ac := randMultiset(rng, sizes[0])
+		b, bc := randMultiset(rng, sizes[1])
+		for _, op := range []struct {
+			name  string
+			f     func(*Tree[int, int], *Tree[int, int]) *Tree[int, int]
+			count func(i, j int) int
+		}{
+			{"Union", (*Tree[int, int]).Union, func(i, j int) int { return max(i, j) }},
+			{"Intersect", (*Tree[int, int]).Intersect, func(i, j int) int { return min(i, j) }},
+			{"Difference", (*Tree[int, int]).Difference, func(i, j int) int { return max(0, i-j) }},
+		} {
+			var want []int
+			for k := 0; k < max(sizes[0], sizes[1]); k++ {
+				for i := 0; i < op.count(ac[k], bc[k]); i++ {
+					want = append(want, k)
+				}
+			}
+			c := op.f(a, b)
+			ensureInvariants(t, c)
+			var got []int
+			c.Do(func(k, _ int) { got = append(got, k) })
+			if !equalInts(got, want) {
+				t.Errorf("%s of sizes %v=%v, expected %v", op.name, sizes, got, want)
+			}
+			if c.Len() != len(want) {
+				t.Errorf("%s of sizes %v has Len %d, expected %d", op.name, sizes, c.Len(), len(want))
+			}
+		}
+	}
+}
+
+// The values of keys in both trees come from the receiver.
+func TestSetOperationValues(t *testing.T) {
+	a := BuildSorted(cmp.Compare[int], []int{1, 2, 3}, []string{"a1", "a2", "a3"})
+	b := BuildSorted(cmp.Compare[int], []int{2, 3, 4}, []string{"b2", "b3", "b4"})
+	for _, test := range []struct {
+		tree *Tree[int, string]
+		want []string
+	}{
+		{a.Union(b), []string{"a1", "a2", "a3", "b4"}},
+		{b.Union(a), []string{"a1", "b2", "b3", "b4"}},
+		{a.Intersect(b), []string{"a2", "a3"}},
+		{a.Difference(b), []string{"a1"}},
+	} {
+		var got []string
+		test.tree.Do(func(_ int, v string) { got = append(got, v) })
+		if len(got) != len(test.want) {
+			t.Errorf("Got values %v, expected %v", got, test.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("Got values %v, expected %v", got, test.want)
+				break
+			}
+		}
+	}
+}
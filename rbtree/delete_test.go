@@ -6,30 +6,22 @@ package rbtree
 
 import "testing"
 
-func s(s string, c color, l *Node, r *Node) *Node {
-	n := newNode(strKey(s), s)
-	n.color = c
-	n.left = l
-	if l != &nilNode {
-		l.parent = n
-	}
-	n.right = r
-	if r != &nilNode {
-		r.parent = n
-	}
-	return n
+// s returns a new node.  A nil child is a leaf;
+// see setRoot.
+func s(s string, c color, l *TreeNode[string, string], r *TreeNode[string, string]) *TreeNode[string, string] {
+	return &TreeNode[string, string]{Key: s, Value: s, color: c, left: l, right: r}
 }
 
-func blk(d int) *Node {
+func blk(d int) *TreeNode[string, string] {
 	if d == 1 {
-		return &nilNode
+		return nil
 	}
 	return s("blk", black, blk(d-1), blk(d-1))
 }
 
 func TestDeleteSingleton(t *testing.T) {
-	tree := New()
-	n := newNode(intKey(1), 1)
+	tree := NewOrdered[int, int]()
+	n := tree.newNode(1, 1)
 	rbInsert(false, tree, n)
 	if tree.root != n {
 		t.Fatalf("Node was never inserted")
@@ -38,43 +30,43 @@ func TestDeleteSingleton(t *testing.T) {
 	if tree.root == n {
 		t.Fatalf("Node was never removed")
 	}
-	if tree.root != &nilNode {
+	if tree.root != tree.nilNode {
 		t.Fatalf("Root is not nilNode")
 	}
 }
 
 func runTestCase4(t *testing.T, c_prime color, right bool) {
-	tree := New()
+	tree := NewOrdered[string, string]()
 	c := black // can't test red because this is the root
 	c_blk_depth := 1
 	if c_prime == red {
 		c_blk_depth = 2
 	}
-	tree.root = s("b", c,
+	setRoot(tree, s("b", c,
 		s("a", black, blk(1), blk(1)),
 		s("d", black, s("c", c_prime,
 			blk(c_blk_depth), blk(c_blk_depth)),
-			s("e", red, blk(2), blk(2))))
+			s("e", red, blk(2), blk(2)))))
 	if right {
 		tree.root = reflect(tree.root)
 	}
 	rbDeleteFixup(tree, tree.root.left)
-	if path("", tree, right).Value.(string) != "d" {
+	if path("", tree, right).Value != "d" {
 		t.Errorf("Root is not d")
 	}
 	if path("", tree, right).color != c {
 		t.Errorf("Root is not d")
 	}
-	if path("l", tree, right).Value.(string) != "b" {
+	if path("l", tree, right).Value != "b" {
 		t.Errorf("b is not left of d")
 	}
-	if path("r", tree, right).Value.(string) != "e" {
+	if path("r", tree, right).Value != "e" {
 		t.Errorf("e is not right of d")
 	}
-	if path("ll", tree, right).Value.(string) != "a" {
+	if path("ll", tree, right).Value != "a" {
 		t.Errorf("a is not left of b")
 	}
-	if path("lr", tree, right).Value.(string) != "c" {
+	if path("lr", tree, right).Value != "c" {
 		t.Errorf("c is not right of b")
 	}
 	if path("lr", tree, right).color != c_prime {
@@ -92,32 +84,32 @@ func TestDeleteCase4(t *testing.T) {
 }
 
 func runTestCase3(t *testing.T, right bool) {
-	tree := New()
-	tree.root = s("b", black,
+	tree := NewOrdered[string, string]()
+	setRoot(tree, s("b", black,
 		s("a", black, blk(1), blk(1)),
 		s("d", black, s("c", red, blk(2), blk(2)),
-			s("e", black, blk(1), blk(1))))
+			s("e", black, blk(1), blk(1)))))
 	if right {
 		tree.root = reflect(tree.root)
 	}
 	x := path("l", tree, right)
-	if x.Value.(string) != "a" {
+	if x.Value != "a" {
 		t.Errorf("node is not a")
 	}
 	rbDeleteFixup(tree, x)
-	if path("", tree, right).Value.(string) != "c" {
+	if path("", tree, right).Value != "c" {
 		t.Errorf("Root is not c")
 	}
-	if path("l", tree, right).Value.(string) != "b" {
+	if path("l", tree, right).Value != "b" {
 		t.Errorf("c left is not b")
 	}
-	if path("r", tree, right).Value.(string) != "d" {
+	if path("r", tree, right).Value != "d" {
 		t.Errorf("c right is not d")
 	}
-	if path("rr", tree, right).Value.(string) != "e" {
+	if path("rr", tree, right).Value != "e" {
 		t.Errorf("d right is not e")
 	}
-	if path("ll", tree, right).Value.(string) != "a" {
+	if path("ll", tree, right).Value != "a" {
 		t.Errorf("b right is not a")
 	}
 	ensureInvariants(t, tree)
@@ -0,0 +1,340 @@
+// Copyright 2011 Ethan Burns
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rbtree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// The binary format of a tree is a header followed by its bindings in
+// ascending order of their keys.
+//
+// The header is:
+//	magic   [4]byte  "RBT\x00"
+//	version uint8    formatVersion
+//	count   uvarint  the number of bindings
+//
+// Each binding is:
+//	size    uvarint  the number of bytes of the encoded key
+//	key     [size]byte
+//	size    uvarint  the number of bytes of the encoded value
+//	value   [size]byte
+//
+// Since the bindings are sorted, a decoded tree is built with
+// BuildSorted in time linear in the number of bindings.
+//
+// The JSON encoding of a tree is an array of its bindings in ascending
+// order of their keys, each an object with "key" and "value" fields.
+
+const (
+	magic         = "RBT\x00"
+	formatVersion = 1
+)
+
+// ErrFormat is returned when decoding a tree that is not
+// in the format written by MarshalBinary.
+var ErrFormat = errors.New("rbtree: invalid format")
+
+// A Codec encodes and decodes the keys or values of a tree.
+type Codec[T any] interface {
+	// Marshal returns the encoding of the key or value.
+	Marshal(T) ([]byte, error)
+
+	// Unmarshal decodes data that was encoded by Marshal.
+	// The byte slice is only valid until Unmarshal returns.
+	Unmarshal([]byte, *T) error
+}
+
+// GobCodec is a Codec that encodes keys or values using encoding/gob.
+// It is the binary Codec used for types that have no registered Codec.
+// Interface types, such as Key, are encoded as interfaces, so the
+// concrete types must be registered with gob.Register.
+//
+// A GobCodec uses a single gob stream for all of the keys, or all of
+// the values, of a tree, so that the gob type information is only
+// written once.  As such, a GobCodec may only be used to encode or
+// decode a single tree, and it must not be registered with
+// RegisterCodec; MarshalBinary and UnmarshalBinary use a new
+// GobCodec for each tree.
+type GobCodec[T any] struct {
+	enc  *gob.Encoder
+	ebuf bytes.Buffer
+	dec  *gob.Decoder
+	dbuf bytes.Buffer
+}
+
+// Marshal implements the Marshal method of the Codec interface.
+func (c *GobCodec[T]) Marshal(x T) ([]byte, error) {
+	if c.enc == nil {
+		c.enc = gob.NewEncoder(&c.ebuf)
+	}
+	c.ebuf.Reset()
+	if err := c.enc.Encode(&x); err != nil {
+		return nil, err
+	}
+	return c.ebuf.Bytes(), nil
+}
+
+// Unmarshal implements the Unmarshal method of the Codec interface.
+func (c *GobCodec[T]) Unmarshal(data []byte, x *T) error {
+	if c.dec == nil {
+		c.dec = gob.NewDecoder(&c.dbuf)
+	}
+	c.dbuf.Reset()
+	c.dbuf.Write(data)
+	if err := c.dec.Decode(x); err != nil {
+		return err
+	}
+	if c.dbuf.Len() != 0 {
+		return fmt.Errorf("%w: %d bytes of data unread", ErrFormat, c.dbuf.Len())
+	}
+	return nil
+}
+
+// JSONCodec is a Codec that encodes keys or values using
+// encoding/json.  It is the JSON Codec used for types that
+// have no registered JSON Codec.
+type JSONCodec[T any] struct{}
+
+// Marshal implements the Marshal method of the Codec interface.
+func (JSONCodec[T]) Marshal(x T) ([]byte, error) {
+	return json.Marshal(x)
+}
+
+// Unmarshal implements the Unmarshal method of the Codec interface.
+func (JSONCodec[T]) Unmarshal(data []byte, x *T) error {
+	return json.Unmarshal(data, x)
+}
+
+type codecKey struct {
+	json bool
+	// typ is a nil *T for keys or values of type T.
+	typ any
+}
+
+// codecs maps a codecKey to the Codec registered for it.
+var codecs sync.Map
+
+// RegisterCodec registers the Codec used by MarshalBinary and
+// UnmarshalBinary for keys or values of type T, replacing any
+// previously registered Codec.  Registering a Codec for an interface
+// type, such as Key, allows its concrete types to be encoded without
+// encoding/gob.
+func RegisterCodec[T any](c Codec[T]) {
+	codecs.Store(codecKey{false, (*T)(nil)}, c)
+}
+
+// RegisterJSONCodec registers the Codec used by MarshalJSON and
+// UnmarshalJSON for keys or values of type T, replacing any previously
+// registered JSON Codec.  The Codec's Marshal method must return a
+// valid JSON value.
+func RegisterJSONCodec[T any](c Codec[T]) {
+	codecs.Store(codecKey{true, (*T)(nil)}, c)
+}
+
+func binaryCodec[T any]() Codec[T] {
+	if c, ok := codecs.Load(codecKey{false, (*T)(nil)}); ok {
+		return c.(Codec[T])
+	}
+	return &GobCodec[T]{}
+}
+
+func jsonCodec[T any]() Codec[T] {
+	if c, ok := codecs.Load(codecKey{true, (*T)(nil)}); ok {
+		return c.(Codec[T])
+	}
+	return JSONCodec[T]{}
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface,
+// encoding the bindings of the tree in ascending order of their keys.
+// Keys and values are encoded with their registered Codecs, or with
+// GobCodec.  Since a tree is a BinaryMarshaler, it can also be encoded
+// with encoding/gob.
+func (t *Tree[K, V]) MarshalBinary() ([]byte, error) {
+	kc, vc := binaryCodec[K](), binaryCodec[V]()
+	buf := append([]byte(magic), formatVersion)
+	buf = binary.AppendUvarint(buf, uint64(t.Len()))
+	var err error
+	t.Ascend(nil, nil, func(k K, v V) bool {
+		var kb, vb []byte
+		if kb, err = kc.Marshal(k); err != nil {
+			return false
+		}
+		if vb, err = vc.Marshal(v); err != nil {
+			return false
+		}
+		buf = binary.AppendUvarint(buf, uint64(len(kb)))
+		buf = append(buf, kb...)
+		buf = binary.AppendUvarint(buf, uint64(len(vb)))
+		buf = append(buf, vb...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface,
+// replacing the bindings of the tree with those decoded from the format
+// written by MarshalBinary.  The tree keeps its comparison function; a
+// zero RbTree orders its keys with Key.Compare.  Keys and values are
+// decoded with their registered Codecs, or with GobCodec.  This
+// operation is O(n) in the number of bindings.
+func (t *Tree[K, V]) UnmarshalBinary(data []byte) error {
+	if err := t.prepare(); err != nil {
+		return err
+	}
+	if len(data) < len(magic)+1 || string(data[:len(magic)]) != magic {
+		return ErrFormat
+	}
+	if v := data[len(magic)]; v != formatVersion {
+		return fmt.Errorf("%w: unknown version %d", ErrFormat, v)
+	}
+	data = data[len(magic)+1:]
+	n, data, err := readUvarint(data)
+	if err != nil {
+		return err
+	}
+	// Each binding is at least two bytes.
+	if n > uint64(len(data))/2 {
+		return fmt.Errorf("%w: too many bindings", ErrFormat)
+	}
+	kc, vc := binaryCodec[K](), binaryCodec[V]()
+	keys := make([]K, n)
+	values := make([]V, n)
+	for i := range keys {
+		var b []byte
+		if b, data, err = readBytes(data); err != nil {
+			return err
+		}
+		if err := kc.Unmarshal(b, &keys[i]); err != nil {
+			return err
+		}
+		if b, data, err = readBytes(data); err != nil {
+			return err
+		}
+		if err := vc.Unmarshal(b, &values[i]); err != nil {
+			return err
+		}
+	}
+	if len(data) > 0 {
+		return fmt.Errorf("%w: %d bytes unread", ErrFormat, len(data))
+	}
+	return t.buildChecked(keys, values)
+}
+
+type jsonBinding struct {
+	Key   json.RawMessage `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// MarshalJSON implements the json.Marshaler interface, encoding the
+// tree as an array of its bindings in ascending order of their keys.
+// Keys and values are encoded with their registered JSON Codecs, or
+// with JSONCodec.
+func (t *Tree[K, V]) MarshalJSON() ([]byte, error) {
+	kc, vc := jsonCodec[K](), jsonCodec[V]()
+	bs := make([]jsonBinding, 0, t.Len())
+	var err error
+	t.Ascend(nil, nil, func(k K, v V) bool {
+		var b jsonBinding
+		if b.Key, err = kc.Marshal(k); err != nil {
+			return false
+		}
+		if b.Value, err = vc.Marshal(v); err != nil {
+			return false
+		}
+		bs = append(bs, b)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(bs)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, replacing
+// the bindings of the tree with those decoded from the format written
+// by MarshalJSON.  The tree keeps its comparison function; a zero
+// RbTree orders its keys with Key.Compare.  Keys and values are decoded
+// with their registered JSON Codecs, or with JSONCodec.
+func (t *Tree[K, V]) UnmarshalJSON(data []byte) error {
+	if err := t.prepare(); err != nil {
+		return err
+	}
+	var bs []jsonBinding
+	if err := json.Unmarshal(data, &bs); err != nil {
+		return err
+	}
+	kc, vc := jsonCodec[K](), jsonCodec[V]()
+	keys := make([]K, len(bs))
+	values := make([]V, len(bs))
+	for i, b := range bs {
+		if err := kc.Unmarshal(b.Key, &keys[i]); err != nil {
+			return err
+		}
+		if err := vc.Unmarshal(b.Value, &values[i]); err != nil {
+			return err
+		}
+	}
+	return t.buildChecked(keys, values)
+}
+
+// prepare readies the tree, which may be a zero Tree, for decoding.
+func (t *Tree[K, V]) prepare() error {
+	if t.cmp == nil {
+		// Key.Compare is only a func(K, K) int if K is Key.
+		c, ok := any(Key.Compare).(func(K, K) int)
+		if !ok {
+			return errors.New("rbtree: decoding into a tree with no comparison function")
+		}
+		t.cmp = c
+	}
+	if t.nilNode == nil {
+		*t = *NewFunc[K, V](t.cmp)
+	}
+	return nil
+}
+
+// buildChecked replaces the tree with the decoded bindings, returning
+// an error if the keys are not sorted.
+func (t *Tree[K, V]) buildChecked(keys []K, values []V) error {
+	for i := 1; i < len(keys); i++ {
+		if t.cmp(keys[i-1], keys[i]) > 0 {
+			return fmt.Errorf("%w: keys are not sorted", ErrFormat)
+		}
+	}
+	t.build(keys, values)
+	return nil
+}
+
+func readUvarint(data []byte) (uint64, []byte, error) {
+	x, n := binary.Uvarint(data)
+	if n <= 0 {
+		return 0, nil, ErrFormat
+	}
+	return x, data[n:], nil
+}
+
+// readBytes returns the length-prefixed byte slice at the
+// front of data and the rest of data.
+func readBytes(data []byte) ([]byte, []byte, error) {
+	n, data, err := readUvarint(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if n > uint64(len(data)) {
+		return nil, nil, fmt.Errorf("%w: truncated binding", ErrFormat)
+	}
+	return data[:n], data[n:], nil
+}
@@ -0,0 +1,239 @@
+// Copyright 2011 Ethan Burns
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rbtree
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+var (
+	_ encoding.BinaryMarshaler   = (*RbTree)(nil)
+	_ encoding.BinaryUnmarshaler = (*RbTree)(nil)
+	_ json.Marshaler             = (*RbTree)(nil)
+	_ json.Unmarshaler           = (*RbTree)(nil)
+)
+
+func init() {
+	gob.Register(intKey(0))
+}
+
+// sameBindings reports an error if the trees do not have the same
+// bindings in the same order.  Values are compared by their printed
+// form, since encoding/json decodes numbers in an interface{} as
+// float64.
+func sameBindings[K, V any](t *testing.T, a, b *Tree[K, V]) {
+	type binding struct {
+		k K
+		v V
+	}
+	var as, bs []binding
+	a.Do(func(k K, v V) { as = append(as, binding{k, v}) })
+	b.Do(func(k K, v V) { bs = append(bs, binding{k, v}) })
+	if len(as) != len(bs) {
+		t.Fatalf("Decoded %d bindings, expected %d", len(bs), len(as))
+	}
+	for i := range as {
+		if a.cmp(as[i].k, bs[i].k) != 0 || fmt.Sprint(as[i].v) != fmt.Sprint(bs[i].v) {
+			t.Errorf("Decoded binding %d is %v, expected %v", i, bs[i], as[i])
+		}
+	}
+}
+
+func randStringTree(n int) *Tree[int, string] {
+	tree := NewOrdered[int, string]()
+	for i := 0; i < n; i++ {
+		k := rand.Intn(n)
+		tree.Add(k, strconv.Itoa(i))
+	}
+	return tree
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 100, 1000} {
+		tree := randStringTree(n)
+		data, err := tree.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary failed: %s", err)
+		}
+		got := NewOrdered[int, string]()
+		got.Add(-1, "replaced")
+		if err := got.UnmarshalBinary(data); err != nil {
+			t.Fatalf("UnmarshalBinary failed: %s", err)
+		}
+		ensureInvariants(t, got)
+		if got.Len() != n {
+			t.Errorf("Decoded tree has Len %d, expected %d", got.Len(), n)
+		}
+		sameBindings(t, tree, got)
+	}
+}
+
+// TestBinaryCompact tests that GobCodec only writes
+// the gob type information once per tree.
+func TestBinaryCompact(t *testing.T) {
+	type value struct{ A, B int }
+	const n = 1000
+	tree := NewOrdered[int, value]()
+	for i := 0; i < n; i++ {
+		tree.Add(i, value{i, -i})
+	}
+	data, err := tree.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %s", err)
+	}
+	// Each binding is a size and gob message for its key and for
+	// its value, which should be well under 24 bytes together once
+	// the types have been described.
+	if max := n * 24; len(data) > max {
+		t.Errorf("MarshalBinary returned %d bytes, expected at most %d", len(data), max)
+	}
+	got := NewOrdered[int, value]()
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary failed: %s", err)
+	}
+	ensureInvariants(t, got)
+	sameBindings(t, tree, got)
+}
+
+func TestBinaryRoundTripKey(t *testing.T) {
+	tree := smallTree(100)
+	data, err := tree.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %s", err)
+	}
+	// The zero RbTree orders its keys with Key.Compare.
+	var got RbTree
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary failed: %s", err)
+	}
+	ensureInvariants(t, &got)
+	sameBindings(t, tree, &got)
+
+	var zero Tree[int, int]
+	if err := zero.UnmarshalBinary(data); err == nil {
+		t.Errorf("Decoding into a zero Tree[int, int] did not fail")
+	}
+}
+
+func TestGobRoundTrip(t *testing.T) {
+	type state struct {
+		Name string
+		Tree *Tree[int, string]
+	}
+	in := state{"trees", randStringTree(100)}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(in); err != nil {
+		t.Fatalf("Encode failed: %s", err)
+	}
+	out := state{Tree: NewOrdered[int, string]()}
+	if err := gob.NewDecoder(&buf).Decode(&out); err != nil {
+		t.Fatalf("Decode failed: %s", err)
+	}
+	if out.Name != in.Name {
+		t.Errorf("Decoded name %q, expected %q", out.Name, in.Name)
+	}
+	ensureInvariants(t, out.Tree)
+	sameBindings(t, in.Tree, out.Tree)
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	tree := NewOrdered[string, int]()
+	tree.Add("b", 2)
+	tree.Add("a", 1)
+	tree.Add("c", 3)
+	data, err := json.Marshal(tree)
+	if err != nil {
+		t.Fatalf("Marshal failed: %s", err)
+	}
+	want := `[{"key":"a","value":1},{"key":"b","value":2},{"key":"c","value":3}]`
+	if string(data) != want {
+		t.Errorf("Marshal=%s, expected %s", data, want)
+	}
+	got := NewOrdered[string, int]()
+	if err := json.Unmarshal(data, got); err != nil {
+		t.Fatalf("Unmarshal failed: %s", err)
+	}
+	ensureInvariants(t, got)
+	sameBindings(t, tree, got)
+
+	unsorted := `[{"key":"b","value":2},{"key":"a","value":1}]`
+	if err := json.Unmarshal([]byte(unsorted), got); !errors.Is(err, ErrFormat) {
+		t.Errorf("Unmarshal of unsorted keys returned %v, expected ErrFormat", err)
+	}
+}
+
+// intKeyCodec encodes intKeys as decimal strings, which are also JSON.
+type intKeyCodec struct{}
+
+func (intKeyCodec) Marshal(k Key) ([]byte, error) {
+	return []byte(strconv.Itoa(int(k.(intKey)))), nil
+}
+
+func (intKeyCodec) Unmarshal(data []byte, k *Key) error {
+	i, err := strconv.Atoi(string(data))
+	*k = intKey(i)
+	return err
+}
+
+func TestRegisterCodec(t *testing.T) {
+	RegisterCodec[Key](intKeyCodec{})
+	RegisterJSONCodec[Key](intKeyCodec{})
+	defer codecs.Delete(codecKey{false, (*Key)(nil)})
+	defer codecs.Delete(codecKey{true, (*Key)(nil)})
+
+	tree := smallTree(10)
+	data, err := tree.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %s", err)
+	}
+	if !bytes.Contains(data, []byte("18")) {
+		t.Errorf("MarshalBinary did not use the registered Codec")
+	}
+	got := New()
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary failed: %s", err)
+	}
+	ensureInvariants(t, got)
+	sameBindings(t, tree, got)
+
+	if data, err = json.Marshal(tree); err != nil {
+		t.Fatalf("Marshal failed: %s", err)
+	}
+	got = New()
+	if err := json.Unmarshal(data, got); err != nil {
+		t.Fatalf("Unmarshal failed: %s", err)
+	}
+	ensureInvariants(t, got)
+	sameBindings(t, tree, got)
+}
+
+func TestUnmarshalBinaryErrors(t *testing.T) {
+	data, err := randStringTree(10).MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %s", err)
+	}
+	badVersion := append([]byte{}, data...)
+	badVersion[len(magic)] = formatVersion + 1
+	for _, bad := range [][]byte{
+		nil,
+		[]byte("RBT"),
+		[]byte("KDT\x00\x01\x00"),
+		badVersion,
+		data[:len(data)-1],
+		append(append([]byte{}, data...), 0),
+	} {
+		if err := NewOrdered[int, string]().UnmarshalBinary(bad); !errors.Is(err, ErrFormat) {
+			t.Errorf("UnmarshalBinary(%q) returned %v, expected ErrFormat", bad, err)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2011 Ethan Burns
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rbtree
+
+import "cmp"
+
+// Interval is the closed interval [Lo, Hi].  Lo must not be
+// greater than Hi.
+type Interval[T cmp.Ordered] struct {
+	Lo, Hi T
+}
+
+// Overlaps returns true if the intervals have a point in common.
+func (i Interval[T]) Overlaps(j Interval[T]) bool {
+	return i.Lo <= j.Hi && j.Lo <= i.Hi
+}
+
+// IntervalTree is a red-black tree that maps Intervals to values of
+// type V, and that can find the Intervals that overlap an interval
+// or a point.  It is the interval tree of CLRS chapter 14.3: a
+// Tree keyed on the Intervals, ordered by Lo and then by Hi, in
+// which each Node is augmented with the maximum Hi in its subtree.
+type IntervalTree[T cmp.Ordered, V any] struct {
+	tree *Tree[Interval[T], intervalValue[T, V]]
+}
+
+type intervalValue[T cmp.Ordered, V any] struct {
+	value V
+	// max is the maximum Hi of the Intervals in the subtree.
+	max T
+}
+
+// NewInterval returns a new empty IntervalTree.
+func NewInterval[T cmp.Ordered, V any]() *IntervalTree[T, V] {
+	t := NewFunc[Interval[T], intervalValue[T, V]](compareIntervals[T])
+	t.update = updateMax[T, V]
+	return &IntervalTree[T, V]{tree: t}
+}
+
+func compareIntervals[T cmp.Ordered](a, b Interval[T]) int {
+	if c := cmp.Compare(a.Lo, b.Lo); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.Hi, b.Hi)
+}
+
+func updateMax[T cmp.Ordered, V any](n *TreeNode[Interval[T], intervalValue[T, V]]) {
+	m := n.Key.Hi
+	if !n.left.isNil() {
+		m = max(m, n.left.Value.max)
+	}
+	if !n.right.isNil() {
+		m = max(m, n.right.Value.max)
+	}
+	n.Value.max = m
+}
+
+// Len returns the number of Intervals in the tree.  This operation is
+// constant in the number of Nodes in the tree.
+func (t *IntervalTree[T, V]) Len() int {
+	return t.tree.Len()
+}
+
+// Add adds a binding from the Interval to the given value.  As with
+// Tree.Add, an Interval that is already in the tree gets an additional
+// binding.  Add panics if i.Lo is greater than i.Hi.  This operation
+// is O(lg n) in the number of Nodes in the tree.
+func (t *IntervalTree[T, V]) Add(i Interval[T], v V) {
+	if i.Lo > i.Hi {
+		panic("rbtree: interval Lo is greater than Hi")
+	}
+	t.tree.Add(i, intervalValue[T, V]{value: v, max: i.Hi})
+}
+
+// Find returns a value bound to the Interval and true, or the zero
+// value and false if the Interval is not in the tree.  This operation
+// is O(lg n) in the number of Nodes in the tree.
+func (t *IntervalTree[T, V]) Find(i Interval[T]) (V, bool) {
+	n := t.tree.Find(i)
+	if n == nil {
+		var v V
+		return v, false
+	}
+	return n.Value.value, true
+}
+
+// Remove removes a binding of the Interval from the tree and returns
+// its value and true, or the zero value and false if the Interval is
+// not in the tree.  This operation is O(lg n) in the number of Nodes
+// in the tree.
+func (t *IntervalTree[T, V]) Remove(i Interval[T]) (V, bool) {
+	n := t.tree.Remove(i)
+	if n == nil {
+		var v V
+		return v, false
+	}
+	return n.Value.value, true
+}
+
+// Do performs the given function on each Interval and its value in
+// ascending order of the Intervals.
+func (t *IntervalTree[T, V]) Do(f func(i Interval[T], v V)) {
+	t.tree.Do(func(i Interval[T], v intervalValue[T, V]) {
+		f(i, v.value)
+	})
+}
+
+// Overlapping calls the function on each Interval in the tree that
+// overlaps the closed interval [lo, hi], and its value, in ascending
+// order of the Intervals.  If the function returns false then the
+// iteration stops.  The subtrees that hold no overlapping Interval
+// are skipped using the maximum Hi of each subtree, so finding the
+// first overlapping Interval is O(lg n) in the number of Nodes in the
+// tree, and finding all k of them is O(min(n, lg n + k lg n)).
+//
+// This is weaker than the O(lg n + k) of a priority search tree or a
+// centered interval tree.  The maximum Hi of a subtree only shows that
+// some Interval in it overlaps, so k overlapping Intervals that are
+// spread through the tree may each be reached through up to lg n
+// Nodes that do not overlap.  Meeting O(lg n + k) would need a
+// different structure than the red-black core shared with Tree.
+func (t *IntervalTree[T, V]) Overlapping(lo, hi T, f func(i Interval[T], v V) bool) {
+	t.overlapping(t.tree.root, Interval[T]{lo, hi}, f)
+}
+
+// Stabbing calls the function on each Interval in the tree that
+// contains the given point, and its value, in ascending order of the
+// Intervals.  It is the same as Overlapping(p, p, f).
+func (t *IntervalTree[T, V]) Stabbing(p T, f func(i Interval[T], v V) bool) {
+	t.Overlapping(p, p, f)
+}
+
+func (t *IntervalTree[T, V]) overlapping(n *TreeNode[Interval[T], intervalValue[T, V]], q Interval[T], f func(Interval[T], V) bool) bool {
+	// Nothing in a subtree overlaps q if all of the subtree's
+	// Intervals end before q.Lo.  Every Interval in the right
+	// subtree starts at or after n.Key.Lo, so nothing there
+	// overlaps q if n.Key starts after q.Hi.
+	for !n.isNil() && n.Value.max >= q.Lo {
+		if !t.overlapping(n.left, q, f) {
+			return false
+		}
+		if n.Key.Lo > q.Hi {
+			break
+		}
+		if n.Key.Hi >= q.Lo && !f(n.Key, n.Value.value) {
+			return false
+		}
+		n = n.right
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+// Copyright 2011 Ethan Burns
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rbtree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func randInterval(rng *rand.Rand, n int) Interval[int] {
+	lo := rng.Intn(n)
+	return Interval[int]{lo, lo + rng.Intn(n/10+1)}
+}
+
+// overlapping returns the Intervals that overlap [lo, hi] in the
+// order that Overlapping visits them.
+func overlapping(tree *IntervalTree[int, int], lo, hi int) []Interval[int] {
+	var is []Interval[int]
+	tree.Overlapping(lo, hi, func(i Interval[int], _ int) bool {
+		is = append(is, i)
+		return true
+	})
+	return is
+}
+
+func TestIntervalTree(t *testing.T) {
+	const n = 1000
+	rng := rand.New(rand.NewSource(0))
+	tree := NewInterval[int, int]()
+	var is []Interval[int]
+	for i := 0; i < n; i++ {
+		iv := randInterval(rng, n)
+		is = append(is, iv)
+		tree.Add(iv, i)
+	}
+	ensureIntervalInvariants(t, tree)
+
+	// Remove half of the Intervals; the rest are is[n/2:].
+	for i := 0; i < n/2; i++ {
+		if _, ok := tree.Remove(is[i]); !ok {
+			t.Fatalf("%v was not found in the tree", is[i])
+		}
+	}
+	ensureIntervalInvariants(t, tree)
+	if tree.Len() != n/2 {
+		t.Errorf("Len is %d, expected %d", tree.Len(), n/2)
+	}
+
+	for q := 0; q < 200; q++ {
+		query := randInterval(rng, n)
+		want := 0
+		for _, iv := range is[n/2:] {
+			if iv.Overlaps(query) {
+				want++
+			}
+		}
+		got := overlapping(tree, query.Lo, query.Hi)
+		for i, iv := range got {
+			if !iv.Overlaps(query) {
+				t.Errorf("Overlapping(%d, %d) visited %v", query.Lo, query.Hi, iv)
+			}
+			if i > 0 && compareIntervals(got[i-1], iv) > 0 {
+				t.Errorf("Overlapping(%d, %d) visited %v after %v", query.Lo, query.Hi, iv, got[i-1])
+			}
+		}
+		if len(got) != want {
+			t.Errorf("Overlapping(%d, %d) visited %d intervals, expected %d",
+				query.Lo, query.Hi, len(got), want)
+		}
+	}
+}
+
+func TestIntervalTreeStabbing(t *testing.T) {
+	tree := NewInterval[float64, string]()
+	tree.Add(Interval[float64]{0, 10}, "a")
+	tree.Add(Interval[float64]{5, 6}, "b")
+	tree.Add(Interval[float64]{6, 8}, "c")
+	tree.Add(Interval[float64]{11, 12}, "d")
+	for _, test := range []struct {
+		p    float64
+		want string
+	}{
+		{-1, ""},
+		{0, "a"},
+		{5.5, "ab"},
+		{6, "abc"},
+		{10.5, ""},
+		{12, "d"},
+	} {
+		got := ""
+		tree.Stabbing(test.p, func(_ Interval[float64], v string) bool {
+			got += v
+			return true
+		})
+		if got != test.want {
+			t.Errorf("Stabbing(%g)=%q, expected %q", test.p, got, test.want)
+		}
+	}
+
+	n := 0
+	tree.Overlapping(0, 12, func(Interval[float64], string) bool {
+		n++
+		return n < 2
+	})
+	if n != 2 {
+		t.Errorf("Overlapping visited %d intervals after returning false, expected 2", n)
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2011 Ethan Burns
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rbtree
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+)
+
+// smallTree returns a tree of the even keys in [0, 2n),
+// with a duplicate binding for each key that is a multiple of 10.
+func smallTree(n int) *RbTree {
+	tree := New()
+	for i := 0; i < n; i++ {
+		tree.Add(intKey(2*i), 2*i)
+		if i%5 == 0 {
+			tree.Add(intKey(2*i), 2*i)
+		}
+	}
+	return tree
+}
+
+func keys(tree *RbTree) []int {
+	var ks []int
+	tree.Do(func(k Key, v interface{}) {
+		ks = append(ks, int(k.(intKey)))
+	})
+	return ks
+}
+
+func TestNextPrev(t *testing.T) {
+	tree := randomTree()
+	ks := keys(tree)
+
+	i := 0
+	for n := tree.Minimum(); n != nil; n = n.Next() {
+		if int(n.Key.(intKey)) != ks[i] {
+			t.Fatalf("Next: key %d is %d, expected %d", i, n.Key, ks[i])
+		}
+		i++
+	}
+	if i != len(ks) {
+		t.Errorf("Next visited %d nodes, expected %d", i, len(ks))
+	}
+
+	i = len(ks) - 1
+	for n := tree.Maximum(); n != nil; n = n.Prev() {
+		if int(n.Key.(intKey)) != ks[i] {
+			t.Fatalf("Prev: key %d is %d, expected %d", i, n.Key, ks[i])
+		}
+		i--
+	}
+	if i != -1 {
+		t.Errorf("Prev visited %d nodes, expected %d", len(ks)-1-i, len(ks))
+	}
+}
+
+func TestIterator(t *testing.T) {
+	tree := smallTree(100)
+	ks := keys(tree)
+
+	it := tree.Iterator()
+	for i := range ks {
+		if it.Node() == nil || int(it.Node().Key.(intKey)) != ks[i] {
+			t.Fatalf("Iterator key %d is %v, expected %d", i, it.Node(), ks[i])
+		}
+		if it.Next() != (i < len(ks)-1) {
+			t.Fatalf("Next returned the wrong value at key %d", i)
+		}
+	}
+	if it.Node() != nil || it.Next() || it.Prev() {
+		t.Errorf("Iterator is not exhausted")
+	}
+
+	if !it.Last() {
+		t.Fatalf("Last returned false on a non-empty tree")
+	}
+	for i := len(ks) - 1; i >= 0; i-- {
+		if int(it.Node().Key.(intKey)) != ks[i] {
+			t.Fatalf("Iterator key %d is %d, expected %d", i, it.Node().Key, ks[i])
+		}
+		it.Prev()
+	}
+	if it.Node() != nil {
+		t.Errorf("Iterator is not exhausted")
+	}
+
+	for k := -1; k <= 2*100; k++ {
+		i := sort.SearchInts(ks, k)
+		if it.Seek(intKey(k)) != (i < len(ks)) {
+			t.Fatalf("Seek(%d) returned the wrong value", k)
+		}
+		if i == len(ks) {
+			continue
+		}
+		// Seek must find the first of any duplicate keys.
+		for ; i < len(ks); i++ {
+			if int(it.Node().Key.(intKey)) != ks[i] {
+				t.Fatalf("After Seek(%d), key %d is %d, expected %d", k, i, it.Node().Key, ks[i])
+			}
+			it.Next()
+		}
+	}
+
+	empty := New().Iterator()
+	if empty.Node() != nil || empty.First() || empty.Last() || empty.Seek(intKey(0)) {
+		t.Errorf("Iterator on an empty tree is not exhausted")
+	}
+}
+
+func TestAscendDescend(t *testing.T) {
+	tree := smallTree(100)
+	ks := keys(tree)
+	bounds := []*Key{nil}
+	for _, b := range []int{-1, 0, 1, 50, 51, 198, 199, 500} {
+		k := Key(intKey(b))
+		bounds = append(bounds, &k)
+	}
+
+	for _, lo := range bounds {
+		for _, hi := range bounds {
+			var want []int
+			for _, k := range ks {
+				if (lo == nil || k >= int((*lo).(intKey))) && (hi == nil || k < int((*hi).(intKey))) {
+					want = append(want, k)
+				}
+			}
+
+			var got []int
+			tree.Ascend(lo, hi, func(k Key, v interface{}) bool {
+				got = append(got, int(k.(intKey)))
+				return true
+			})
+			if !equalInts(got, want) {
+				t.Errorf("Ascend(%s, %s)=%v, expected %v", bound(lo), bound(hi), got, want)
+			}
+
+			got = got[:0]
+			tree.Descend(lo, hi, func(k Key, v interface{}) bool {
+				got = append(got, int(k.(intKey)))
+				return true
+			})
+			for i, j := 0, len(want)-1; i < j; i, j = i+1, j-1 {
+				want[i], want[j] = want[j], want[i]
+			}
+			if !equalInts(got, want) {
+				t.Errorf("Descend(%s, %s)=%v, expected %v", bound(lo), bound(hi), got, want)
+			}
+		}
+	}
+
+	n := 0
+	tree.Ascend(nil, nil, func(Key, interface{}) bool {
+		n++
+		return n < 5
+	})
+	if n != 5 {
+		t.Errorf("Ascend visited %d nodes after returning false, expected 5", n)
+	}
+	n = 0
+	tree.Descend(nil, nil, func(Key, interface{}) bool {
+		n++
+		return n < 5
+	})
+	if n != 5 {
+		t.Errorf("Descend visited %d nodes after returning false, expected 5", n)
+	}
+}
+
+func bound(k *Key) string {
+	if k == nil {
+		return "nil"
+	}
+	return fmt.Sprint(*k)
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
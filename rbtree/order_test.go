@@ -0,0 +1,84 @@
+// Copyright 2011 Ethan Burns
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rbtree
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestFloorCeiling(t *testing.T) {
+	tree := smallTree(100)
+	ks := keys(tree)
+
+	for k := -2; k <= 2*100+1; k++ {
+		i := sort.SearchInts(ks, k+1) - 1
+		n := tree.Floor(intKey(k))
+		switch {
+		case i < 0 && n != nil:
+			t.Errorf("Floor(%d)=%v, expected nil", k, n.Key)
+		case i >= 0 && (n == nil || int(n.Key.(intKey)) != ks[i]):
+			t.Errorf("Floor(%d)=%v, expected %d", k, n, ks[i])
+		}
+
+		i = sort.SearchInts(ks, k)
+		n = tree.Ceiling(intKey(k))
+		switch {
+		case i == len(ks) && n != nil:
+			t.Errorf("Ceiling(%d)=%v, expected nil", k, n.Key)
+		case i < len(ks) && (n == nil || int(n.Key.(intKey)) != ks[i]):
+			t.Errorf("Ceiling(%d)=%v, expected %d", k, n, ks[i])
+		}
+	}
+
+	empty := New()
+	if empty.Floor(intKey(0)) != nil || empty.Ceiling(intKey(0)) != nil {
+		t.Errorf("Floor or Ceiling of an empty tree is not nil")
+	}
+}
+
+func TestSelectRank(t *testing.T) {
+	tree := randomTree()
+	// Remove some nodes to exercise the size updates in rbDelete.
+	for i := 0; i < nAdds/2; i++ {
+		tree.RemoveNode(tree.Select(rand.Intn(tree.Len())))
+	}
+	ensureInvariants(t, tree)
+	ks := keys(tree)
+
+	for i, k := range ks {
+		n := tree.Select(i)
+		if n == nil || int(n.Key.(intKey)) != k {
+			t.Fatalf("Select(%d)=%v, expected %d", i, n, k)
+		}
+		if r := tree.Rank(intKey(k)); r != sort.SearchInts(ks, k) {
+			t.Fatalf("Rank(%d)=%d, expected %d", k, r, sort.SearchInts(ks, k))
+		}
+		if r := tree.Rank(intKey(k + 1)); r != sort.SearchInts(ks, k+1) {
+			t.Fatalf("Rank(%d)=%d, expected %d", k+1, r, sort.SearchInts(ks, k+1))
+		}
+	}
+	if n := tree.Select(-1); n != nil {
+		t.Errorf("Select(-1)=%v, expected nil", n.Key)
+	}
+	if n := tree.Select(len(ks)); n != nil {
+		t.Errorf("Select(%d)=%v, expected nil", len(ks), n.Key)
+	}
+}
+
+func TestSelectRankDuplicates(t *testing.T) {
+	tree := smallTree(100)
+	ks := keys(tree)
+	for k := -1; k <= 2*100; k++ {
+		r := tree.Rank(intKey(k))
+		if r != sort.SearchInts(ks, k) {
+			t.Errorf("Rank(%d)=%d, expected %d", k, r, sort.SearchInts(ks, k))
+		}
+		if n := tree.Ceiling(intKey(k)); n != tree.Select(r) {
+			t.Errorf("Select(Rank(%d)) is not Ceiling(%d)", k, k)
+		}
+	}
+}
@@ -0,0 +1,365 @@
+// Copyright 2011 Ethan Burns
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rbtree
+
+import "cmp"
+
+/* The persistent tree is not from CLRS.  Path copying needs nodes
+that can be shared between many versions of a tree, so a node can have
+neither a parent pointer nor a shared sentinel leaf that is written to
+(CLRS's deletion sets the parent of T.nil).  Instead, the persistent
+tree is a left-leaning red-black tree from "Left-leaning Red-Black
+Trees" by Robert Sedgewick, which needs neither, with nil leaves.
+Every function that changes a node first copies it, so each operation
+copies only the O(lg n) nodes on its search path. */
+
+// Persistent is an immutable red-black tree that maps keys of type
+// K to values of type V.  Add, Replace and Remove return a new
+// version of the tree that shares all of its unchanged subtrees with
+// the old version, which remains valid.  Since a version is never
+// modified, any number of goroutines may read it concurrently,
+// even while others make new versions from it.
+type Persistent[K, V any] struct {
+	root *pnode[K, V]
+	len  int
+	cmp  func(a, b K) int
+}
+
+type pnode[K, V any] struct {
+	left  *pnode[K, V]
+	right *pnode[K, V]
+	color color
+	key   K
+	value V
+}
+
+// NewPersistent returns a new empty persistent tree that can map
+// Keys to values of type interface{}.
+func NewPersistent() *Persistent[Key, interface{}] {
+	return NewPersistentFunc[Key, interface{}](Key.Compare)
+}
+
+// NewPersistentFunc returns a new empty persistent tree that orders
+// its keys using the given comparison function, as with NewFunc.
+func NewPersistentFunc[K, V any](cmp func(a, b K) int) *Persistent[K, V] {
+	return &Persistent[K, V]{cmp: cmp}
+}
+
+// NewPersistentOrdered returns a new empty persistent tree that
+// orders its keys using cmp.Compare.
+func NewPersistentOrdered[K cmp.Ordered, V any]() *Persistent[K, V] {
+	return NewPersistentFunc[K, V](cmp.Compare[K])
+}
+
+// Len returns the number of key/value mappings in the tree.  This
+// operation is constant in the number of nodes in the tree.
+func (t *Persistent[K, V]) Len() int {
+	return t.len
+}
+
+// Find returns the value bound to the given key and true, or the
+// zero value and false if the key is not in the tree.  This operation
+// is O(lg n) in the number of nodes in the tree.
+func (t *Persistent[K, V]) Find(k K) (V, bool) {
+	if n := t.find(k); n != nil {
+		return n.value, true
+	}
+	var v V
+	return v, false
+}
+
+func (t *Persistent[K, V]) find(k K) *pnode[K, V] {
+	n := t.root
+	for n != nil {
+		switch c := t.cmp(k, n.key); {
+		case c == 0:
+			return n
+		case c < 0:
+			n = n.left
+		default:
+			n = n.right
+		}
+	}
+	return nil
+}
+
+// Member returns true if the given key is in the tree.
+func (t *Persistent[K, V]) Member(k K) bool {
+	return t.find(k) != nil
+}
+
+// Minimum returns the smallest key in the tree and its value.
+// If the tree is empty then ok is false.
+func (t *Persistent[K, V]) Minimum() (k K, v V, ok bool) {
+	n := t.root
+	if n == nil {
+		return k, v, false
+	}
+	for n.left != nil {
+		n = n.left
+	}
+	return n.key, n.value, true
+}
+
+// Maximum returns the largest key in the tree and its value.
+// If the tree is empty then ok is false.
+func (t *Persistent[K, V]) Maximum() (k K, v V, ok bool) {
+	n := t.root
+	if n == nil {
+		return k, v, false
+	}
+	for n.right != nil {
+		n = n.right
+	}
+	return n.key, n.value, true
+}
+
+// Do performs the given function on each key/value pair in
+// ascending order of the keys.
+func (t *Persistent[K, V]) Do(f func(k K, v V)) {
+	pInOrder(t.root, f)
+}
+
+func pInOrder[K, V any](n *pnode[K, V], f func(k K, v V)) {
+	for n != nil {
+		pInOrder(n.left, f)
+		f(n.key, n.value)
+		n = n.right
+	}
+}
+
+// Ascend calls f on each key/value pair with a key in the range
+// [lo, hi) in ascending order, stopping early if f returns false.
+// A nil bound leaves that end of the range unbounded.
+func (t *Persistent[K, V]) Ascend(lo, hi *K, f func(k K, v V) bool) {
+	t.ascend(t.root, lo, hi, f)
+}
+
+func (t *Persistent[K, V]) ascend(n *pnode[K, V], lo, hi *K, f func(k K, v V) bool) bool {
+	for n != nil {
+		if lo != nil && t.cmp(n.key, *lo) < 0 {
+			n = n.right
+			continue
+		}
+		if !t.ascend(n.left, lo, hi, f) {
+			return false
+		}
+		if hi != nil && t.cmp(n.key, *hi) >= 0 {
+			return false
+		}
+		if !f(n.key, n.value) {
+			return false
+		}
+		// Every key in the right subtree is at least lo.
+		lo = nil
+		n = n.right
+	}
+	return true
+}
+
+// Descend calls f on each key/value pair with a key in the range
+// [lo, hi) in descending order, stopping early if f returns false.
+// A nil bound leaves that end of the range unbounded.
+func (t *Persistent[K, V]) Descend(lo, hi *K, f func(k K, v V) bool) {
+	t.descend(t.root, lo, hi, f)
+}
+
+func (t *Persistent[K, V]) descend(n *pnode[K, V], lo, hi *K, f func(k K, v V) bool) bool {
+	for n != nil {
+		if hi != nil && t.cmp(n.key, *hi) >= 0 {
+			n = n.left
+			continue
+		}
+		if !t.descend(n.right, lo, hi, f) {
+			return false
+		}
+		if lo != nil && t.cmp(n.key, *lo) < 0 {
+			return false
+		}
+		if !f(n.key, n.value) {
+			return false
+		}
+		// Every key in the left subtree is less than hi.
+		hi = nil
+		n = n.left
+	}
+	return true
+}
+
+// Add returns a new version of the tree with the given key bound to
+// the given value.  As with Tree.Add, a key that is already in the
+// tree gets an additional binding.  This operation is O(lg n) in the
+// number of nodes in the tree.
+func (t *Persistent[K, V]) Add(k K, v V) *Persistent[K, V] {
+	return t.insert(false, k, v)
+}
+
+// Replace returns a new version of the tree with the given key bound
+// to the given value, replacing the key's previous binding if it has
+// one.  This operation is O(lg n) in the number of nodes in the tree.
+func (t *Persistent[K, V]) Replace(k K, v V) *Persistent[K, V] {
+	return t.insert(true, k, v)
+}
+
+func (t *Persistent[K, V]) insert(replace bool, k K, v V) *Persistent[K, V] {
+	u := &Persistent[K, V]{len: t.len, cmp: t.cmp}
+	u.root = u.insertNode(replace, t.root, k, v)
+	u.root.color = black
+	return u
+}
+
+func (t *Persistent[K, V]) insertNode(replace bool, h *pnode[K, V], k K, v V) *pnode[K, V] {
+	if h == nil {
+		t.len += 1
+		return &pnode[K, V]{color: red, key: k, value: v}
+	}
+	h = h.clone()
+	switch c := t.cmp(k, h.key); {
+	case replace && c == 0:
+		h.value = v
+	case c < 0:
+		h.left = t.insertNode(replace, h.left, k, v)
+	default:
+		h.right = t.insertNode(replace, h.right, k, v)
+	}
+	return balance(h)
+}
+
+// Remove returns a new version of the tree with a binding of the
+// given key removed.  If the key is not in the tree then the tree
+// itself is returned.  This operation is O(lg n) in the number of
+// nodes in the tree.
+func (t *Persistent[K, V]) Remove(k K) *Persistent[K, V] {
+	if !t.Member(k) {
+		return t
+	}
+	u := &Persistent[K, V]{len: t.len - 1, cmp: t.cmp}
+	h := t.root
+	if !isRed(h.left) && !isRed(h.right) {
+		h = h.clone()
+		h.color = red
+	}
+	u.root = u.removeNode(h, k)
+	if u.root != nil {
+		u.root.color = black
+	}
+	return u
+}
+
+// RemoveNode removes k from the subtree rooted at h, which
+// must contain it.
+func (t *Persistent[K, V]) removeNode(h *pnode[K, V], k K) *pnode[K, V] {
+	h = h.clone()
+	if t.cmp(k, h.key) < 0 {
+		if !isRed(h.left) && !isRed(h.left.left) {
+			h = moveRedLeft(h)
+		}
+		h.left = t.removeNode(h.left, k)
+		return balance(h)
+	}
+	if isRed(h.left) {
+		h = rotateRight(h)
+	}
+	if t.cmp(k, h.key) == 0 && h.right == nil {
+		return nil
+	}
+	if !isRed(h.right) && !isRed(h.right.left) {
+		h = moveRedRight(h)
+	}
+	if t.cmp(k, h.key) == 0 {
+		m := h.right
+		for m.left != nil {
+			m = m.left
+		}
+		h.key, h.value = m.key, m.value
+		h.right = removeMin(h.right)
+	} else {
+		h.right = t.removeNode(h.right, k)
+	}
+	return balance(h)
+}
+
+func removeMin[K, V any](h *pnode[K, V]) *pnode[K, V] {
+	if h.left == nil {
+		return nil
+	}
+	h = h.clone()
+	if !isRed(h.left) && !isRed(h.left.left) {
+		h = moveRedLeft(h)
+	}
+	h.left = removeMin(h.left)
+	return balance(h)
+}
+
+func (n *pnode[K, V]) clone() *pnode[K, V] {
+	m := *n
+	return &m
+}
+
+func isRed[K, V any](n *pnode[K, V]) bool {
+	return n != nil && n.color == red
+}
+
+// The following functions modify h, which must be a copy that
+// is not shared with any other version of the tree.  Any other
+// node that they modify is copied first.
+
+func rotateLeft[K, V any](h *pnode[K, V]) *pnode[K, V] {
+	x := h.right.clone()
+	h.right = x.left
+	x.left = h
+	x.color = h.color
+	h.color = red
+	return x
+}
+
+func rotateRight[K, V any](h *pnode[K, V]) *pnode[K, V] {
+	x := h.left.clone()
+	h.left = x.right
+	x.right = h
+	x.color = h.color
+	h.color = red
+	return x
+}
+
+func flipColors[K, V any](h *pnode[K, V]) {
+	h.color = !h.color
+	h.left = h.left.clone()
+	h.left.color = !h.left.color
+	h.right = h.right.clone()
+	h.right.color = !h.right.color
+}
+
+func moveRedLeft[K, V any](h *pnode[K, V]) *pnode[K, V] {
+	flipColors(h)
+	if isRed(h.right.left) {
+		h.right = rotateRight(h.right)
+		h = rotateLeft(h)
+		flipColors(h)
+	}
+	return h
+}
+
+func moveRedRight[K, V any](h *pnode[K, V]) *pnode[K, V] {
+	flipColors(h)
+	if isRed(h.left.left) {
+		h = rotateRight(h)
+		flipColors(h)
+	}
+	return h
+}
+
+func balance[K, V any](h *pnode[K, V]) *pnode[K, V] {
+	if isRed(h.right) && !isRed(h.left) {
+		h = rotateLeft(h)
+	}
+	if isRed(h.left) && isRed(h.left.left) {
+		h = rotateRight(h)
+	}
+	if isRed(h.left) && isRed(h.right) {
+		flipColors(h)
+	}
+	return h
+}
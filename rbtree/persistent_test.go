@@ -0,0 +1,193 @@
+// Copyright 2011 Ethan Burns
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rbtree
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// pKeys returns the keys of the tree in the order visited by Do.
+func pKeys(tree *Persistent[int, int]) []int {
+	var ks []int
+	tree.Do(func(k, _ int) { ks = append(ks, k) })
+	return ks
+}
+
+// Every version of the tree must keep the keys that it had when
+// it was made, no matter what is done to later versions.
+func TestPersistentAddRemove(t *testing.T) {
+	const n = 2000
+	perm := rand.Perm(n)
+	versions := []*Persistent[int, int]{NewPersistentOrdered[int, int]()}
+	for _, k := range perm {
+		versions = append(versions, versions[len(versions)-1].Add(k, -k))
+	}
+	for _, k := range perm[:n/2] {
+		versions = append(versions, versions[len(versions)-1].Remove(k))
+	}
+
+	for i, tree := range versions {
+		ensurePersistentInvariants(t, tree)
+		var want []int
+		if i <= n {
+			want = append(want, perm[:i]...)
+		} else {
+			want = append(want, perm[i-n:]...)
+		}
+		sort.Ints(want)
+		if got := pKeys(tree); !equalInts(got, want) {
+			t.Fatalf("Version %d has keys %v, expected %v", i, got, want)
+		}
+		for _, k := range want {
+			if v, ok := tree.Find(k); !ok || v != -k {
+				t.Fatalf("Version %d: Find(%d)=%d, %t, expected %d, true", i, k, v, ok, -k)
+			}
+		}
+	}
+
+	last := versions[len(versions)-1]
+	if last.Remove(-1) != last {
+		t.Errorf("Removing a missing key made a new version")
+	}
+	for _, k := range perm[n/2:] {
+		last = last.Remove(k)
+		ensurePersistentInvariants(t, last)
+	}
+	if last.Len() != 0 || last.root != nil {
+		t.Errorf("Not all nodes were removed")
+	}
+}
+
+func TestPersistentDuplicates(t *testing.T) {
+	tree := NewPersistentOrdered[int, int]()
+	for i := 0; i < 100; i++ {
+		tree = tree.Add(i%10, i)
+	}
+	ensurePersistentInvariants(t, tree)
+	if tree.Len() != 100 {
+		t.Errorf("Len is %d, expected 100", tree.Len())
+	}
+	replaced := tree.Replace(5, -1)
+	if replaced.Len() != 100 {
+		t.Errorf("Replace added a binding")
+	}
+	if v, _ := tree.Find(5); v == -1 {
+		t.Errorf("Replace changed the old version")
+	}
+	for i := 0; i < 10; i++ {
+		tree = tree.Remove(5)
+		ensurePersistentInvariants(t, tree)
+	}
+	if tree.Member(5) || tree.Len() != 90 {
+		t.Errorf("Not all bindings of 5 were removed")
+	}
+}
+
+func TestPersistentMinimumMaximum(t *testing.T) {
+	tree := NewPersistent()
+	if _, _, ok := tree.Minimum(); ok {
+		t.Errorf("Minimum of an empty tree is ok")
+	}
+	if _, _, ok := tree.Maximum(); ok {
+		t.Errorf("Maximum of an empty tree is ok")
+	}
+	for _, k := range rand.Perm(100) {
+		tree = tree.Add(intKey(k), k)
+	}
+	if k, v, _ := tree.Minimum(); k != intKey(0) || v != 0 {
+		t.Errorf("Minimum is %v, %v, expected 0, 0", k, v)
+	}
+	if k, v, _ := tree.Maximum(); k != intKey(99) || v != 99 {
+		t.Errorf("Maximum is %v, %v, expected 99, 99", k, v)
+	}
+}
+
+func TestPersistentAscendDescend(t *testing.T) {
+	tree := NewPersistentOrdered[int, int]()
+	for _, k := range rand.Perm(100) {
+		tree = tree.Add(2*k, k)
+	}
+	ks := pKeys(tree)
+	bounds := []*int{nil}
+	for _, b := range []int{-1, 0, 1, 50, 51, 198, 199, 500} {
+		b := b
+		bounds = append(bounds, &b)
+	}
+	for _, lo := range bounds {
+		for _, hi := range bounds {
+			var want []int
+			for _, k := range ks {
+				if (lo == nil || k >= *lo) && (hi == nil || k < *hi) {
+					want = append(want, k)
+				}
+			}
+			var got []int
+			tree.Ascend(lo, hi, func(k, _ int) bool {
+				got = append(got, k)
+				return true
+			})
+			if !equalInts(got, want) {
+				t.Errorf("Ascend=%v, expected %v", got, want)
+			}
+
+			got = got[:0]
+			tree.Descend(lo, hi, func(k, _ int) bool {
+				got = append(got, k)
+				return true
+			})
+			for i, j := 0, len(want)-1; i < j; i, j = i+1, j-1 {
+				want[i], want[j] = want[j], want[i]
+			}
+			if !equalInts(got, want) {
+				t.Errorf("Descend=%v, expected %v", got, want)
+			}
+		}
+	}
+}
+
+// Readers of old versions run concurrently with a writer making new
+// versions.  Run with -race to check that versions are never written.
+func TestPersistentConcurrent(t *testing.T) {
+	const n = 1000
+	tree := NewPersistentOrdered[int, int]()
+	for i := 0; i < n; i++ {
+		tree = tree.Add(i, i)
+	}
+	snapshots := make(chan *Persistent[int, int])
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for s := range snapshots {
+				sum, count := 0, 0
+				s.Do(func(k, v int) {
+					sum += v
+					count++
+				})
+				if count != s.Len() {
+					t.Errorf("Snapshot has %d nodes, but its Len is %d", count, s.Len())
+				}
+				for k := 0; k < n; k += 97 {
+					s.Find(k)
+				}
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		snapshots <- tree
+		if i%2 == 0 {
+			tree = tree.Remove(i)
+		} else {
+			tree = tree.Replace(i, -i)
+		}
+	}
+	close(snapshots)
+	wg.Wait()
+	ensurePersistentInvariants(t, tree)
+}
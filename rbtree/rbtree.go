@@ -10,6 +10,8 @@
 // and Stein.
 package rbtree
 
+import "cmp"
+
 /* In my opinion, some of this code is really terrible looking.  The
 reason is that it is a direct translation from CLRS (see package
 comment).  The disadvantage is that the code is difficult to read on
@@ -19,7 +21,7 @@ the pseudo-code in CLRS and therefore the book documents the code
 
 type color bool
 
-// Key is the interface for the keys associated with data in the tree.
+// Key is the interface for the keys associated with data in an RbTree.
 type Key interface {
 	// Compare checks the ordering of two keys.  It returns
 	// <0 if the receiver is less than the argument, >0 if the
@@ -27,61 +29,94 @@ type Key interface {
 	Compare(Key) int
 }
 
-// Node represents a node in the tree.  It can be used as an opaque
-// handle to the tree node holding the association between a Key
+// TreeNode represents a node in a Tree.  It can be used as an opaque
+// handle to the tree node holding the association between a key
 // and its data.
-type Node struct {
-	parent *Node
-	left   *Node
-	right  *Node
+type TreeNode[K, V any] struct {
+	parent *TreeNode[K, V]
+	left   *TreeNode[K, V]
+	right  *TreeNode[K, V]
 	color  color
-	Key    Key
-	Value  interface{}
+	// size is the number of Nodes in the subtree rooted here.
+	size  int
+	Key   K
+	Value V
 }
 
-// RbTree is a red-black tree that can map Keys to values of type
-// interface{}.
-type RbTree struct {
-	root *Node
+// Tree is a red-black tree that maps keys of type K to values
+// of type V.
+type Tree[K, V any] struct {
+	root *TreeNode[K, V]
 	len  int
+	cmp  func(a, b K) int
+
+	// nilNode is the sentinel leaf of the tree (T.nil in CLRS).
+	nilNode *TreeNode[K, V]
+
+	// update, if non-nil, is called on a Node whenever its
+	// subtree changes, after its size is recomputed and after
+	// update is called on its children.  It is used to augment
+	// the Nodes with more information about their subtrees.
+	update func(n *TreeNode[K, V])
 }
 
+// RbTree is a red-black tree that can map Keys to values of type
+// interface{}.
+type RbTree = Tree[Key, interface{}]
+
+// Node represents a node in an RbTree.
+type Node = TreeNode[Key, interface{}]
+
 const (
 	red   color = true
 	black color = false
 )
 
-var nilNode Node
-
-func init() {
-	nilNode.color = black
-	nilNode.parent = &nilNode
-	nilNode.left = &nilNode
-	nilNode.right = &nilNode
+// IsNil returns true if the Node is a tree's sentinel nilNode.
+// The sentinel is the only Node that is its own child.
+func (n *TreeNode[K, V]) isNil() bool {
+	return n.left == n
 }
 
-func newNode(k Key, v interface{}) *Node {
-	return &Node{left: &nilNode, right: &nilNode, parent: &nilNode, Key: k, Value: v}
+func (t *Tree[K, V]) newNode(k K, v V) *TreeNode[K, V] {
+	return &TreeNode[K, V]{left: t.nilNode, right: t.nilNode, parent: t.nilNode, size: 1, Key: k, Value: v}
 }
 
-// New returns a new empty tree.
+// New returns a new empty RbTree.
 func New() *RbTree {
-	return &RbTree{root: &nilNode}
+	return NewFunc[Key, interface{}](Key.Compare)
+}
+
+// NewFunc returns a new empty tree that orders its keys using the
+// given comparison function.  The function must return <0 if a is
+// less than b, >0 if a is greater than b and 0 if they are equal.
+func NewFunc[K, V any](cmp func(a, b K) int) *Tree[K, V] {
+	nilNode := &TreeNode[K, V]{color: black}
+	nilNode.parent = nilNode
+	nilNode.left = nilNode
+	nilNode.right = nilNode
+	return &Tree[K, V]{root: nilNode, cmp: cmp, nilNode: nilNode}
+}
+
+// NewOrdered returns a new empty tree that orders its keys
+// using cmp.Compare.
+func NewOrdered[K cmp.Ordered, V any]() *Tree[K, V] {
+	return NewFunc[K, V](cmp.Compare[K])
 }
 
 // Len returns the number of key/value mappings in the tree.  This operation
 // is constant in the number of Nodes in the tree.
-func (t *RbTree) Len() int {
+func (t *Tree[K, V]) Len() int {
 	return t.len
 }
 
 // Find returns a pointer to a node that associates the matching Key
 // to a value or nil if there is no mapping for the given Key in the tree.
 // This operation is O(lg n) in the number of Nodes in the tree.
-func (t *RbTree) Find(k Key) *Node {
+func (t *Tree[K, V]) Find(k K) *TreeNode[K, V] {
 	x := t.root
-	for x != &nilNode {
-		switch cmp := k.Compare(x.Key); {
+	for x != t.nilNode {
+		switch cmp := t.cmp(k, x.Key); {
 		case cmp == 0:
 			return x
 		case cmp < 0:
@@ -94,7 +129,7 @@ func (t *RbTree) Find(k Key) *Node {
 }
 
 // Member tests if there is a value bound to the given Key in the tree.
-func (t *RbTree) Member(k Key) bool {
+func (t *Tree[K, V]) Member(k K) bool {
 	return t.Find(k) != nil
 }
 
@@ -102,12 +137,12 @@ func (t *RbTree) Member(k Key) bool {
 // is called on the bindings in the ordering defined over the Keys. The
 // behavior is undefined if f modifies the tree during traversal. The
 // operation is O(n) in the number of Nodes in the tree.
-func (t *RbTree) Do(f func(k Key, v interface{})) {
+func (t *Tree[K, V]) Do(f func(k K, v V)) {
 	inOrder(t.root, f)
 }
 
-func inOrder(n *Node, f func(k Key, v interface{})) {
-	if n != &nilNode {
+func inOrder[K, V any](n *TreeNode[K, V], f func(k K, v V)) {
+	if !n.isNil() {
 		inOrder(n.left, f)
 		f(n.Key, n.Value)
 		inOrder(n.right, f)
@@ -118,8 +153,8 @@ func inOrder(n *Node, f func(k Key, v interface{})) {
 // key to the given value and returns a pointer to the Node
 // associated with this binding.  This operation is O(lg n) in
 // the number of Nodes in the tree.
-func (t *RbTree) Add(k Key, v interface{}) *Node {
-	n := newNode(k, v)
+func (t *Tree[K, V]) Add(k K, v V) *TreeNode[K, V] {
+	n := t.newNode(k, v)
 	t.len += 1
 	return rbInsert(false, t, n)
 }
@@ -127,11 +162,15 @@ func (t *RbTree) Add(k Key, v interface{}) *Node {
 // Reinsert re-inserts the given Node into the tree.  The Node must
 // represent a node that has been removed from the tree.  This
 // operation is O(lg n) in the number of Nodes in the tree.
-func (t *RbTree) Reinsert(n *Node) {
+func (t *Tree[K, V]) Reinsert(n *TreeNode[K, V]) {
 	if n != nil {
-		n.left = &nilNode
-		n.right = &nilNode
-		n.parent = &nilNode
+		n.left = t.nilNode
+		n.right = t.nilNode
+		n.parent = t.nilNode
+		n.size = 1
+		if t.update != nil {
+			t.update(n)
+		}
 		t.len += 1
 		rbInsert(false, t, n)
 	}
@@ -141,7 +180,7 @@ func (t *RbTree) Reinsert(n *Node) {
 // This should be called whenever the key is changed and the
 // Node is still in the tree.  This operation is O(lg n) in the number
 // of Nodes in the tree.
-func (t *RbTree) UpdateKey(n *Node) {
+func (t *Tree[K, V]) UpdateKey(n *TreeNode[K, V]) {
 	if n != nil {
 		t.RemoveNode(n)
 		t.Reinsert(n)
@@ -154,8 +193,8 @@ func (t *RbTree) UpdateKey(n *Node) {
 // value.  If there is no value previously bound to the Key then
 // a new binding is added.  This operation is O(lg n) in the number
 // of Nodes in the tree.
-func (t *RbTree) Replace(k Key, v interface{}) *Node {
-	n := newNode(k, v)
+func (t *Tree[K, V]) Replace(k K, v V) *TreeNode[K, V] {
+	n := t.newNode(k, v)
 	m := rbInsert(true, t, n)
 	if m == n {
 		t.len += 1
@@ -163,15 +202,15 @@ func (t *RbTree) Replace(k Key, v interface{}) *Node {
 	return m
 }
 
-func leftRotate(t *RbTree, x *Node) {
+func leftRotate[K, V any](t *Tree[K, V], x *TreeNode[K, V]) {
 	y := x.right
 	x.right = y.left
-	if y.left != &nilNode {
+	if y.left != t.nilNode {
 		y.left.parent = x
 	}
 	y.parent = x.parent
 	switch {
-	case x.parent == &nilNode:
+	case x.parent == t.nilNode:
 		t.root = y
 	case x == x.parent.left:
 		x.parent.left = y
@@ -180,17 +219,23 @@ func leftRotate(t *RbTree, x *Node) {
 	}
 	y.left = x
 	x.parent = y
+	y.size = x.size
+	x.size = x.left.size + x.right.size + 1
+	if t.update != nil {
+		t.update(x)
+		t.update(y)
+	}
 }
 
-func rightRotate(t *RbTree, x *Node) {
+func rightRotate[K, V any](t *Tree[K, V], x *TreeNode[K, V]) {
 	y := x.left
 	x.left = y.right
-	if y.right != &nilNode {
+	if y.right != t.nilNode {
 		y.right.parent = x
 	}
 	y.parent = x.parent
 	switch {
-	case x.parent == &nilNode:
+	case x.parent == t.nilNode:
 		t.root = y
 	case x == x.parent.right:
 		x.parent.right = y
@@ -199,16 +244,22 @@ func rightRotate(t *RbTree, x *Node) {
 	}
 	y.right = x
 	x.parent = y
+	y.size = x.size
+	x.size = x.left.size + x.right.size + 1
+	if t.update != nil {
+		t.update(x)
+		t.update(y)
+	}
 }
 
 // If replace is true then an equal element found in the tree will be
 // replaced with the value from z instead of adding z.
-func rbInsert(replace bool, t *RbTree, z *Node) *Node {
-	y := &nilNode
+func rbInsert[K, V any](replace bool, t *Tree[K, V], z *TreeNode[K, V]) *TreeNode[K, V] {
+	y := t.nilNode
 	x := t.root
-	for x != &nilNode {
+	for x != t.nilNode {
 		y = x
-		switch cmp := z.Key.Compare(x.Key); {
+		switch cmp := t.cmp(z.Key, x.Key); {
 		case replace && cmp == 0:
 			x.Value = z.Value
 			return x
@@ -220,19 +271,25 @@ func rbInsert(replace bool, t *RbTree, z *Node) *Node {
 	}
 	z.parent = y
 	switch {
-	case y == &nilNode:
+	case y == t.nilNode:
 		t.root = z
-	case z.Key.Compare(y.Key) < 0:
+	case t.cmp(z.Key, y.Key) < 0:
 		y.left = z
 	default:
 		y.right = z
 	}
+	for ; y != t.nilNode; y = y.parent {
+		y.size += 1
+		if t.update != nil {
+			t.update(y)
+		}
+	}
 	z.color = red
 	rbInsertFixup(t, z)
 	return z
 }
 
-func rbInsertFixup(t *RbTree, z *Node) {
+func rbInsertFixup[K, V any](t *Tree[K, V], z *TreeNode[K, V]) {
 	for z.parent.color == red {
 		if z.parent == z.parent.parent.left {
 			y := z.parent.parent.right
@@ -274,12 +331,12 @@ func rbInsertFixup(t *RbTree, z *Node) {
 // Minimum returns a pointer to the node in the tree that holds the
 // minimum key according to the comparison function over Keys.
 // This operation is O(lg n) in the number of Nodes in the tree.
-func (t *RbTree) Minimum() *Node {
+func (t *Tree[K, V]) Minimum() *TreeNode[K, V] {
 	return treeMinimum(t.root)
 }
 
-func treeMinimum(x *Node) *Node {
-	for ; x.left != &nilNode; x = x.left {
+func treeMinimum[K, V any](x *TreeNode[K, V]) *TreeNode[K, V] {
+	for ; !x.left.isNil(); x = x.left {
 	}
 	return x
 }
@@ -287,12 +344,12 @@ func treeMinimum(x *Node) *Node {
 // Maximum returns a pointer to the node in the tree that holds the
 // maximum key according to the comparison function over Keys.
 // This operation is O(lg n) in the number of Nodes in the tree.
-func (t *RbTree) Maximum() *Node {
+func (t *Tree[K, V]) Maximum() *TreeNode[K, V] {
 	return treeMaximum(t.root)
 }
 
-func treeMaximum(x *Node) *Node {
-	for ; x.right != &nilNode; x = x.right {
+func treeMaximum[K, V any](x *TreeNode[K, V]) *TreeNode[K, V] {
+	for ; !x.right.isNil(); x = x.right {
 	}
 	return x
 }
@@ -301,7 +358,7 @@ func treeMaximum(x *Node) *Node {
 // binding then nil is returned otherwise a pointer to the Node for the
 // removed binding is returned.  This operation is O(lg n) in the number
 // of Nodes in the tree.
-func (t *RbTree) Remove(k Key) *Node {
+func (t *Tree[K, V]) Remove(k K) *TreeNode[K, V] {
 	n := t.Find(k)
 	if n != nil {
 		t.len -= 1
@@ -314,22 +371,22 @@ func (t *RbTree) Remove(k Key) *Node {
 // is O(lg n) in the number of Nodes in the tree but can often be much
 // faster than calling Remove on the Key as this method elides the
 // loopkup step to find the Node.
-func (t *RbTree) RemoveNode(n *Node) {
+func (t *Tree[K, V]) RemoveNode(n *TreeNode[K, V]) {
 	if n != nil {
 		t.len -= 1
 		rbDelete(t, n)
 	}
 }
 
-func rbDelete(t *RbTree, z *Node) {
-	var x *Node
+func rbDelete[K, V any](t *Tree[K, V], z *TreeNode[K, V]) {
+	var x *TreeNode[K, V]
 	y := z
 	yOriginalColor := y.color
 	switch {
-	case z.left == &nilNode:
+	case z.left == t.nilNode:
 		x = z.right
 		rbTransplant(t, z, z.right)
-	case z.right == &nilNode:
+	case z.right == t.nilNode:
 		x = z.left
 		rbTransplant(t, z, z.left)
 	default:
@@ -348,14 +405,22 @@ func rbDelete(t *RbTree, z *Node) {
 		y.left.parent = y
 		y.color = z.color
 	}
+	// Every Node on the path from x up to the root
+	// has lost a Node from its subtree.
+	for p := x.parent; p != t.nilNode; p = p.parent {
+		p.size = p.left.size + p.right.size + 1
+		if t.update != nil {
+			t.update(p)
+		}
+	}
 	if yOriginalColor == black {
 		rbDeleteFixup(t, x)
 	}
 }
 
-func rbTransplant(t *RbTree, u *Node, v *Node) {
+func rbTransplant[K, V any](t *Tree[K, V], u *TreeNode[K, V], v *TreeNode[K, V]) {
 	switch {
-	case u.parent == &nilNode:
+	case u.parent == t.nilNode:
 		t.root = v
 	case u == u.parent.left:
 		u.parent.left = v
@@ -365,7 +430,7 @@ func rbTransplant(t *RbTree, u *Node, v *Node) {
 	v.parent = u.parent
 }
 
-func rbDeleteFixup(t *RbTree, x *Node) {
+func rbDeleteFixup[K, V any](t *Tree[K, V], x *TreeNode[K, V]) {
 	for x != t.root && x.color == black {
 		if x == x.parent.left {
 			w := x.parent.right
@@ -420,19 +485,279 @@ func rbDeleteFixup(t *RbTree, x *Node) {
 	x.color = black
 }
 
-func copy(n *Node) *Node {
-	if n == &nilNode {
-		return n
+func (t *Tree[K, V]) copy(nilNode, n *TreeNode[K, V]) *TreeNode[K, V] {
+	if n == nilNode {
+		return t.nilNode
+	}
+	m := t.newNode(n.Key, n.Value)
+	m.color = n.color
+	m.size = n.size
+	m.left = t.copy(nilNode, n.left)
+	if m.left != t.nilNode {
+		m.left.parent = m
+	}
+	m.right = t.copy(nilNode, n.right)
+	if m.right != t.nilNode {
+		m.right.parent = m
 	}
-	m := newNode(n.Key, n.Value)
-	l := copy(n.left)
-	l.parent = m
-	r := copy(n.right)
-	r.parent = m
 	return m
 }
 
 // Copy returns a copy of the given tree.
-func (t *RbTree) Copy() *RbTree {
-	return &RbTree{root: copy(t.root), len: t.len}
+func (t *Tree[K, V]) Copy() *Tree[K, V] {
+	c := NewFunc[K, V](t.cmp)
+	c.update = t.update
+	c.root = c.copy(t.nilNode, t.root)
+	c.len = t.len
+	return c
+}
+
+// Next returns the Node with the next greater key in the tree,
+// or nil if this is the Node with the maximum key.  This operation
+// is O(lg n) in the number of Nodes in the tree, but iterating
+// over the entire tree with Next is O(n).
+func (n *TreeNode[K, V]) Next() *TreeNode[K, V] {
+	if !n.right.isNil() {
+		return treeMinimum(n.right)
+	}
+	y := n.parent
+	for !y.isNil() && n == y.right {
+		n = y
+		y = y.parent
+	}
+	if y.isNil() {
+		return nil
+	}
+	return y
+}
+
+// Prev returns the Node with the next lesser key in the tree,
+// or nil if this is the Node with the minimum key.  This operation
+// is O(lg n) in the number of Nodes in the tree, but iterating
+// over the entire tree with Prev is O(n).
+func (n *TreeNode[K, V]) Prev() *TreeNode[K, V] {
+	if !n.left.isNil() {
+		return treeMaximum(n.left)
+	}
+	y := n.parent
+	for !y.isNil() && n == y.left {
+		n = y
+		y = y.parent
+	}
+	if y.isNil() {
+		return nil
+	}
+	return y
+}
+
+// lowerBound returns the Node with the minimum key that is not
+// less than k, or nil if all keys are less than k.
+func (t *Tree[K, V]) lowerBound(k K) *TreeNode[K, V] {
+	var y *TreeNode[K, V]
+	x := t.root
+	for x != t.nilNode {
+		if t.cmp(k, x.Key) <= 0 {
+			y = x
+			x = x.left
+		} else {
+			x = x.right
+		}
+	}
+	return y
+}
+
+// upperBound returns the Node with the minimum key that is
+// greater than k, or nil if no key is greater than k.
+func (t *Tree[K, V]) upperBound(k K) *TreeNode[K, V] {
+	var y *TreeNode[K, V]
+	x := t.root
+	for x != t.nilNode {
+		if t.cmp(k, x.Key) < 0 {
+			y = x
+			x = x.left
+		} else {
+			x = x.right
+		}
+	}
+	return y
+}
+
+// Iterator is a cursor over the Nodes of a tree in the ordering
+// defined over the Keys.  An Iterator is either positioned at a
+// Node of the tree or it is exhausted, in which case its Node is
+// nil.  The behavior is undefined if the tree is modified while
+// the Iterator is in use.
+type Iterator[K, V any] struct {
+	tree *Tree[K, V]
+	node *TreeNode[K, V]
+}
+
+// Iterator returns a new Iterator positioned at the Node with
+// the minimum key in the tree.
+func (t *Tree[K, V]) Iterator() *Iterator[K, V] {
+	it := &Iterator[K, V]{tree: t}
+	it.First()
+	return it
+}
+
+// Node returns the Node at which the Iterator is positioned,
+// or nil if the Iterator is exhausted.
+func (it *Iterator[K, V]) Node() *TreeNode[K, V] {
+	return it.node
+}
+
+// First positions the Iterator at the Node with the minimum
+// key in the tree.  It returns false if the tree is empty.
+func (it *Iterator[K, V]) First() bool {
+	it.node = nil
+	if it.tree.root != it.tree.nilNode {
+		it.node = treeMinimum(it.tree.root)
+	}
+	return it.node != nil
+}
+
+// Last positions the Iterator at the Node with the maximum
+// key in the tree.  It returns false if the tree is empty.
+func (it *Iterator[K, V]) Last() bool {
+	it.node = nil
+	if it.tree.root != it.tree.nilNode {
+		it.node = treeMaximum(it.tree.root)
+	}
+	return it.node != nil
+}
+
+// Seek positions the Iterator at the Node with the minimum key
+// that is not less than the given Key.  It returns false if there
+// is no such Node.  This operation is O(lg n) in the number of
+// Nodes in the tree.
+func (it *Iterator[K, V]) Seek(k K) bool {
+	it.node = it.tree.lowerBound(k)
+	return it.node != nil
+}
+
+// Next moves the Iterator to the Node with the next greater key.
+// It returns false if there is no such Node, in which case the
+// Iterator is exhausted.
+func (it *Iterator[K, V]) Next() bool {
+	if it.node != nil {
+		it.node = it.node.Next()
+	}
+	return it.node != nil
+}
+
+// Prev moves the Iterator to the Node with the next lesser key.
+// It returns false if there is no such Node, in which case the
+// Iterator is exhausted.
+func (it *Iterator[K, V]) Prev() bool {
+	if it.node != nil {
+		it.node = it.node.Prev()
+	}
+	return it.node != nil
+}
+
+// Ascend calls the function on each Key/value pair in the tree with
+// a key in the range [*lo, *hi), in ascending order of the keys.  A nil
+// lo or hi leaves that end of the range unbounded.  If the function
+// returns false then the iteration stops.  The behavior is undefined
+// if f modifies the tree during traversal.
+func (t *Tree[K, V]) Ascend(lo, hi *K, f func(k K, v V) bool) {
+	var n *TreeNode[K, V]
+	switch {
+	case lo != nil:
+		n = t.lowerBound(*lo)
+	case t.root != t.nilNode:
+		n = treeMinimum(t.root)
+	}
+	for ; n != nil; n = n.Next() {
+		if hi != nil && t.cmp(n.Key, *hi) >= 0 || !f(n.Key, n.Value) {
+			return
+		}
+	}
+}
+
+// Descend calls the function on each Key/value pair in the tree with
+// a key in the range [*lo, *hi), in descending order of the keys.  A nil
+// lo or hi leaves that end of the range unbounded.  If the function
+// returns false then the iteration stops.  The behavior is undefined
+// if f modifies the tree during traversal.
+func (t *Tree[K, V]) Descend(lo, hi *K, f func(k K, v V) bool) {
+	if t.root == t.nilNode {
+		return
+	}
+	n := treeMaximum(t.root)
+	if hi != nil {
+		if m := t.lowerBound(*hi); m != nil {
+			n = m.Prev()
+		}
+	}
+	for ; n != nil; n = n.Prev() {
+		if lo != nil && t.cmp(n.Key, *lo) < 0 || !f(n.Key, n.Value) {
+			return
+		}
+	}
+}
+
+// Floor returns a pointer to the node in the tree that holds the
+// greatest key that is less than or equal to the given Key, or nil
+// if there is no such key.  This operation is O(lg n) in the number
+// of Nodes in the tree.
+func (t *Tree[K, V]) Floor(k K) *TreeNode[K, V] {
+	var y *TreeNode[K, V]
+	x := t.root
+	for x != t.nilNode {
+		if t.cmp(k, x.Key) >= 0 {
+			y = x
+			x = x.right
+		} else {
+			x = x.left
+		}
+	}
+	return y
+}
+
+// Ceiling returns a pointer to the node in the tree that holds the
+// least key that is greater than or equal to the given Key, or nil
+// if there is no such key.  This operation is O(lg n) in the number
+// of Nodes in the tree.
+func (t *Tree[K, V]) Ceiling(k K) *TreeNode[K, V] {
+	return t.lowerBound(k)
+}
+
+// Select returns a pointer to the node in the tree that holds the
+// ith smallest key, counting from zero, or nil if i is not in the
+// range [0, t.Len()).  This operation is O(lg n) in the number of
+// Nodes in the tree.
+func (t *Tree[K, V]) Select(i int) *TreeNode[K, V] {
+	x := t.root
+	for x != t.nilNode {
+		r := x.left.size
+		switch {
+		case i < r:
+			x = x.left
+		case i == r:
+			return x
+		default:
+			i -= r + 1
+			x = x.right
+		}
+	}
+	return nil
+}
+
+// Rank returns the number of keys in the tree that are less than
+// the given Key.  If the Key is in the tree then Select(Rank(k))
+// is the first node that holds it.  This operation is O(lg n) in
+// the number of Nodes in the tree.
+func (t *Tree[K, V]) Rank(k K) int {
+	r := 0
+	x := t.root
+	for x != t.nilNode {
+		if t.cmp(k, x.Key) > 0 {
+			r += x.left.size + 1
+			x = x.right
+		} else {
+			x = x.left
+		}
+	}
+	return r
 }
@@ -92,10 +92,72 @@ func TestMaximumAndMinimum(t *testing.T) {
 	tree.Do(iter)
 	if min != tree.Minimum().Value.(int) {
 		t.Errorf("Minimum is %d, Minimum() reported %d instead\n",
-			min, tree.Minimum())
+			min, tree.Minimum().Value.(int))
 	}
 	if max != tree.Maximum().Value.(int) {
 		t.Errorf("Maximum is %d, Maximum() reported %d instead\n",
 			max, tree.Maximum().Value.(int))
 	}
 }
+
+func TestCopy(t *testing.T) {
+	tree := smallTree(100)
+	cp := tree.Copy()
+	ensureInvariants(t, cp)
+	if cp.Len() != tree.Len() || countNodes(cp.root) != tree.Len() {
+		t.Errorf("Copy has %d nodes, expected %d", countNodes(cp.root), tree.Len())
+	}
+	if !equalInts(keys(cp), keys(tree)) {
+		t.Errorf("Copy has keys %v, expected %v", keys(cp), keys(tree))
+	}
+	cp.Remove(intKey(0))
+	if tree.Find(intKey(0)) == nil {
+		t.Errorf("Removing from a Copy modified the original tree")
+	}
+}
+
+// A descending comparator should order the tree in reverse.
+func TestNewFunc(t *testing.T) {
+	tree := NewFunc[int, string](func(a, b int) int { return b - a })
+	for i := 0; i < 100; i++ {
+		tree.Add(i, "")
+	}
+	ensureInvariants(t, tree)
+	if tree.Minimum().Key != 99 || tree.Maximum().Key != 0 {
+		t.Errorf("Minimum=%d, Maximum=%d, expected 99 and 0",
+			tree.Minimum().Key, tree.Maximum().Key)
+	}
+	i := 99
+	tree.Do(func(k int, _ string) {
+		if k != i {
+			t.Errorf("Do visited %d, expected %d", k, i)
+		}
+		i--
+	})
+}
+
+func TestNewOrdered(t *testing.T) {
+	tree := NewOrdered[string, int]()
+	for i, k := range []string{"b", "d", "a", "c"} {
+		tree.Add(k, i)
+	}
+	if n := tree.Replace("a", 10); n.Key != "a" || tree.Len() != 4 {
+		t.Errorf("Replace added a new node")
+	}
+	if n := tree.Find("a"); n == nil || n.Value != 10 {
+		t.Errorf("Find(a) did not find the replaced value")
+	}
+	n := tree.Find("d")
+	n.Key = "0"
+	tree.UpdateKey(n)
+	if tree.Minimum() != n {
+		t.Errorf("UpdateKey did not move the node to the minimum")
+	}
+	c := tree.Copy()
+	tree.RemoveNode(n)
+	ensureInvariants(t, tree)
+	ensureInvariants(t, c)
+	if tree.Len() != 3 || c.Len() != 4 || c.Find("0") == nil {
+		t.Errorf("Removing from a tree changed its copy")
+	}
+}
@@ -8,12 +8,12 @@ import "testing"
 
 // Example from CLRS ed 3 Figure 13.2
 func TestLeftRotateRoot(t *testing.T) {
-	tree := New()
-	y := emptyNode()
-	x := emptyNode()
-	alph := emptyNode()
-	beta := emptyNode()
-	gamma := emptyNode()
+	tree := NewOrdered[int, int]()
+	y := emptyNode(tree)
+	x := emptyNode(tree)
+	alph := emptyNode(tree)
+	beta := emptyNode(tree)
+	gamma := emptyNode(tree)
 	tree.root = x
 	x.left = alph
 	alph.parent = x
@@ -44,12 +44,12 @@ func TestLeftRotateRoot(t *testing.T) {
 
 // Example from CLRS ed 3 Figure 13.2
 func TestRightRotateRoot(t *testing.T) {
-	tree := New()
-	y := emptyNode()
-	x := emptyNode()
-	alph := emptyNode()
-	beta := emptyNode()
-	gamma := emptyNode()
+	tree := NewOrdered[int, int]()
+	y := emptyNode(tree)
+	x := emptyNode(tree)
+	alph := emptyNode(tree)
+	beta := emptyNode(tree)
+	gamma := emptyNode(tree)
 	tree.root = y
 	y.left = x
 	x.parent = y
@@ -80,13 +80,13 @@ func TestRightRotateRoot(t *testing.T) {
 
 // Example from CLRS ed 3 Figure 13.2
 func TestLeftRotateInternal(t *testing.T) {
-	tree := New()
-	top := emptyNode()
-	y := emptyNode()
-	x := emptyNode()
-	alph := emptyNode()
-	beta := emptyNode()
-	gamma := emptyNode()
+	tree := NewOrdered[int, int]()
+	top := emptyNode(tree)
+	y := emptyNode(tree)
+	x := emptyNode(tree)
+	alph := emptyNode(tree)
+	beta := emptyNode(tree)
+	gamma := emptyNode(tree)
 	tree.root = top
 	top.left = x
 	x.parent = top
@@ -122,13 +122,13 @@ func TestLeftRotateInternal(t *testing.T) {
 
 // Example from CLRS ed 3 Figure 13.2
 func TestRightRotateInternal(t *testing.T) {
-	tree := New()
-	top := emptyNode()
-	y := emptyNode()
-	x := emptyNode()
-	alph := emptyNode()
-	beta := emptyNode()
-	gamma := emptyNode()
+	tree := NewOrdered[int, int]()
+	top := emptyNode(tree)
+	y := emptyNode(tree)
+	x := emptyNode(tree)
+	alph := emptyNode(tree)
+	beta := emptyNode(tree)
+	gamma := emptyNode(tree)
 	tree.root = top
 	top.left = y
 	y.parent = top
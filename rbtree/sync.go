@@ -0,0 +1,297 @@
+// Copyright 2011 Ethan Burns
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rbtree
+
+import (
+	"cmp"
+	"sync"
+)
+
+// SyncTree is a red-black tree that is safe for concurrent use by
+// multiple goroutines.  Any number of goroutines may read the tree at
+// once, but a goroutine that modifies it has exclusive access.
+//
+// Since a Node may be modified by a concurrent write, a SyncTree
+// never returns Nodes; the methods that read the tree return copies
+// of keys and values instead.
+type SyncTree[K, V any] struct {
+	mu   sync.RWMutex
+	tree *Tree[K, V]
+}
+
+// NewSync returns a new empty SyncTree that can map Keys to
+// values of type interface{}.
+func NewSync() *SyncTree[Key, interface{}] {
+	return NewSyncFunc[Key, interface{}](Key.Compare)
+}
+
+// NewSyncFunc returns a new empty SyncTree that orders its keys
+// using the given comparison function, as with NewFunc.
+func NewSyncFunc[K, V any](cmp func(a, b K) int) *SyncTree[K, V] {
+	return &SyncTree[K, V]{tree: NewFunc[K, V](cmp)}
+}
+
+// NewSyncOrdered returns a new empty SyncTree that orders its
+// keys using cmp.Compare.
+func NewSyncOrdered[K cmp.Ordered, V any]() *SyncTree[K, V] {
+	return NewSyncFunc[K, V](cmp.Compare[K])
+}
+
+// Len returns the number of key/value mappings in the tree.
+func (t *SyncTree[K, V]) Len() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.tree.Len()
+}
+
+// Find returns the value bound to the given key and true, or the
+// zero value and false if the key is not in the tree.
+func (t *SyncTree[K, V]) Find(k K) (V, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return value(t.tree.Find(k))
+}
+
+// Member returns true if the given key is in the tree.
+func (t *SyncTree[K, V]) Member(k K) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.tree.Member(k)
+}
+
+// Minimum returns the smallest key in the tree and its value.
+// If the tree is empty then ok is false.
+func (t *SyncTree[K, V]) Minimum() (k K, v V, ok bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return binding(t.tree.Minimum())
+}
+
+// Maximum returns the largest key in the tree and its value.
+// If the tree is empty then ok is false.
+func (t *SyncTree[K, V]) Maximum() (k K, v V, ok bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return binding(t.tree.Maximum())
+}
+
+// Add adds a binding from the given key to the given value, as with
+// Tree.Add.
+func (t *SyncTree[K, V]) Add(k K, v V) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.tree.Add(k, v)
+}
+
+// Replace binds the given key to the given value, replacing the
+// key's previous binding if it has one, as with Tree.Replace.
+func (t *SyncTree[K, V]) Replace(k K, v V) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.tree.Replace(k, v)
+}
+
+// Remove removes a binding of the given key from the tree and
+// returns its value and true, or the zero value and false if the
+// key is not in the tree.
+func (t *SyncTree[K, V]) Remove(k K) (V, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return value(t.tree.Remove(k))
+}
+
+// Do performs the given function on each key/value pair in
+// ascending order of the keys.  The iteration sees a point-in-time
+// view of the tree: the key/value pairs are copied while the tree is
+// read locked, and f is called on the copies after the lock is
+// released, so f may call any SyncTree method, including those
+// that modify the tree.  This operation is O(n) in the number of
+// Nodes in the tree.
+func (t *SyncTree[K, V]) Do(f func(k K, v V)) {
+	type kv struct {
+		k K
+		v V
+	}
+	t.mu.RLock()
+	kvs := make([]kv, 0, t.tree.Len())
+	t.tree.Do(func(k K, v V) { kvs = append(kvs, kv{k, v}) })
+	t.mu.RUnlock()
+	for _, b := range kvs {
+		f(b.k, b.v)
+	}
+}
+
+// Snapshot returns a copy of the tree as it is at the time of
+// the call.  The copy is not shared, so it can be read without
+// locking and iterated with a point-in-time view, for example
+// with its Iterator.  This operation is O(n) in the number of
+// Nodes in the tree; Persistent trees have O(1) snapshots.
+func (t *SyncTree[K, V]) Snapshot() *Tree[K, V] {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.tree.Copy()
+}
+
+// Ascend calls the function on each key/value pair in the tree with
+// a key in the range [*lo, *hi), in ascending order of the keys, as
+// with Tree.Ascend.  The iteration is weakly consistent, as with a
+// SyncIterator, and the tree is not locked while f is called, so f
+// may modify the tree.
+func (t *SyncTree[K, V]) Ascend(lo, hi *K, f func(k K, v V) bool) {
+	it := t.Iterator()
+	ok := it.First()
+	if lo != nil {
+		ok = it.Seek(*lo)
+	}
+	for ; ok; ok = it.Next() {
+		if hi != nil && t.tree.cmp(it.Key(), *hi) >= 0 || !f(it.Key(), it.Value()) {
+			return
+		}
+	}
+}
+
+// Descend calls the function on each key/value pair in the tree with
+// a key in the range [*lo, *hi), in descending order of the keys, as
+// with Tree.Descend.  The iteration is weakly consistent, as with a
+// SyncIterator, and the tree is not locked while f is called, so f
+// may modify the tree.
+func (t *SyncTree[K, V]) Descend(lo, hi *K, f func(k K, v V) bool) {
+	it := t.Iterator()
+	ok := it.Last()
+	if hi != nil {
+		ok = it.seekBelow(*hi)
+	}
+	for ; ok; ok = it.Prev() {
+		if lo != nil && t.tree.cmp(it.Key(), *lo) < 0 || !f(it.Key(), it.Value()) {
+			return
+		}
+	}
+}
+
+// SyncIterator is a weakly-consistent cursor over the key/value pairs
+// of a SyncTree in the ordering defined over the keys.  The tree is
+// only locked during the calls to the SyncIterator's methods, so the
+// tree may be modified while the SyncIterator is in use.
+//
+// Instead of holding a Node, a SyncIterator holds a copy of the key
+// and value at which it is positioned, and each move is an O(lg n)
+// search for the next key from that key.  As a result, a SyncIterator
+// never visits the same key twice and always moves in key order.  A
+// key that is in the tree for the whole iteration is visited, but a
+// key that is added or removed during the iteration may or may not
+// be.  If a key has more than one binding, then only one is visited.
+type SyncIterator[K, V any] struct {
+	tree  *SyncTree[K, V]
+	ok    bool
+	key   K
+	value V
+}
+
+// Iterator returns a new SyncIterator positioned at the minimum key
+// in the tree.
+func (t *SyncTree[K, V]) Iterator() *SyncIterator[K, V] {
+	it := &SyncIterator[K, V]{tree: t}
+	it.First()
+	return it
+}
+
+// Valid returns false if the SyncIterator is exhausted.
+func (it *SyncIterator[K, V]) Valid() bool {
+	return it.ok
+}
+
+// Key returns the key at which the SyncIterator is positioned, or
+// the zero value if the SyncIterator is exhausted.
+func (it *SyncIterator[K, V]) Key() K {
+	return it.key
+}
+
+// Value returns the value bound to the key at which the SyncIterator
+// is positioned when the SyncIterator was moved to it.  It returns
+// the zero value if the SyncIterator is exhausted.
+func (it *SyncIterator[K, V]) Value() V {
+	return it.value
+}
+
+// First positions the SyncIterator at the minimum key in the tree.
+// It returns false if the tree is empty.
+func (it *SyncIterator[K, V]) First() bool {
+	it.tree.mu.RLock()
+	defer it.tree.mu.RUnlock()
+	return it.set(it.tree.tree.Minimum())
+}
+
+// Last positions the SyncIterator at the maximum key in the tree.
+// It returns false if the tree is empty.
+func (it *SyncIterator[K, V]) Last() bool {
+	it.tree.mu.RLock()
+	defer it.tree.mu.RUnlock()
+	return it.set(it.tree.tree.Maximum())
+}
+
+// Seek positions the SyncIterator at the minimum key that is not
+// less than the given key.  It returns false if there is no such key.
+func (it *SyncIterator[K, V]) Seek(k K) bool {
+	it.tree.mu.RLock()
+	defer it.tree.mu.RUnlock()
+	return it.set(it.tree.tree.lowerBound(k))
+}
+
+// seekBelow positions the SyncIterator at the maximum key that is
+// less than the given key.  It returns false if there is no such key.
+func (it *SyncIterator[K, V]) seekBelow(k K) bool {
+	it.tree.mu.RLock()
+	defer it.tree.mu.RUnlock()
+	if n := it.tree.tree.lowerBound(k); n != nil {
+		return it.set(n.Prev())
+	}
+	return it.set(it.tree.tree.Maximum())
+}
+
+// Next moves the SyncIterator to the next greater key that is in
+// the tree.  It returns false if there is no such key, in which case
+// the SyncIterator is exhausted.
+func (it *SyncIterator[K, V]) Next() bool {
+	if !it.ok {
+		return false
+	}
+	it.tree.mu.RLock()
+	defer it.tree.mu.RUnlock()
+	return it.set(it.tree.tree.upperBound(it.key))
+}
+
+// Prev moves the SyncIterator to the next lesser key that is in the
+// tree.  It returns false if there is no such key, in which case the
+// SyncIterator is exhausted.
+func (it *SyncIterator[K, V]) Prev() bool {
+	if !it.ok {
+		return false
+	}
+	return it.seekBelow(it.key)
+}
+
+// set positions the SyncIterator at a copy of the binding in n,
+// or exhausts it if n is nil.
+func (it *SyncIterator[K, V]) set(n *TreeNode[K, V]) bool {
+	it.key, it.value, it.ok = binding(n)
+	return it.ok
+}
+
+// value and binding return copies of the value and binding in n.
+// They return false if n is nil or is the sentinel, which Minimum
+// and Maximum return for an empty tree.
+func value[K, V any](n *TreeNode[K, V]) (v V, ok bool) {
+	if n == nil || n.isNil() {
+		return v, false
+	}
+	return n.Value, true
+}
+
+func binding[K, V any](n *TreeNode[K, V]) (k K, v V, ok bool) {
+	if n == nil || n.isNil() {
+		return k, v, false
+	}
+	return n.Key, n.Value, true
+}
@@ -0,0 +1,213 @@
+// Copyright 2011 Ethan Burns
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rbtree
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+func TestSyncTree(t *testing.T) {
+	tree := NewSync()
+	if _, _, ok := tree.Minimum(); ok {
+		t.Errorf("Minimum of an empty tree is ok")
+	}
+	if it := tree.Iterator(); it.Valid() || it.Next() || it.Last() {
+		t.Errorf("Iterator on an empty tree is not exhausted")
+	}
+	for _, k := range rand.Perm(100) {
+		tree.Add(intKey(k), k)
+	}
+	if v, ok := tree.Find(intKey(50)); !ok || v != 50 {
+		t.Errorf("Find(50)=%v, %t, expected 50, true", v, ok)
+	}
+	if v, ok := tree.Remove(intKey(50)); !ok || v != 50 || tree.Member(intKey(50)) {
+		t.Errorf("Remove(50)=%v, %t, expected 50, true", v, ok)
+	}
+	if _, ok := tree.Remove(intKey(50)); ok {
+		t.Errorf("Removed 50 twice")
+	}
+	if k, _, _ := tree.Minimum(); k != intKey(0) {
+		t.Errorf("Minimum is %v, expected 0", k)
+	}
+	if k, _, _ := tree.Maximum(); k != intKey(99) {
+		t.Errorf("Maximum is %v, expected 99", k)
+	}
+	s := tree.Snapshot()
+	tree.Remove(intKey(0))
+	if s.Len() != 99 || !s.Member(intKey(0)) || tree.Len() != 98 {
+		t.Errorf("Modifying the tree changed its snapshot")
+	}
+}
+
+func TestSyncTreeAscendDescend(t *testing.T) {
+	tree := NewSyncOrdered[int, int]()
+	for _, k := range rand.Perm(100) {
+		tree.Add(2*k, k)
+	}
+	var ks []int
+	tree.Do(func(k, _ int) { ks = append(ks, k) })
+	bounds := []*int{nil}
+	for _, b := range []int{-1, 0, 1, 50, 51, 198, 199, 500} {
+		b := b
+		bounds = append(bounds, &b)
+	}
+	for _, lo := range bounds {
+		for _, hi := range bounds {
+			var want []int
+			for _, k := range ks {
+				if (lo == nil || k >= *lo) && (hi == nil || k < *hi) {
+					want = append(want, k)
+				}
+			}
+			var got []int
+			tree.Ascend(lo, hi, func(k, _ int) bool {
+				got = append(got, k)
+				return true
+			})
+			if !equalInts(got, want) {
+				t.Errorf("Ascend=%v, expected %v", got, want)
+			}
+
+			got = got[:0]
+			tree.Descend(lo, hi, func(k, _ int) bool {
+				got = append(got, k)
+				return true
+			})
+			for i, j := 0, len(want)-1; i < j; i, j = i+1, j-1 {
+				want[i], want[j] = want[j], want[i]
+			}
+			if !equalInts(got, want) {
+				t.Errorf("Descend=%v, expected %v", got, want)
+			}
+		}
+	}
+}
+
+// Ascend's function may modify the tree.  Keys that are in the tree
+// for the whole iteration must be visited, in order, exactly once.
+func TestSyncTreeWeaklyConsistent(t *testing.T) {
+	tree := NewSyncOrdered[int, int]()
+	for k := 0; k < 100; k += 2 {
+		tree.Add(k, k)
+	}
+	var got []int
+	tree.Ascend(nil, nil, func(k, _ int) bool {
+		got = append(got, k)
+		if k%2 == 0 {
+			tree.Remove(k)
+			tree.Add(k+1, k+1)
+			tree.Add(k-1, k-1)
+		}
+		return true
+	})
+	for i := 1; i < len(got); i++ {
+		if got[i] <= got[i-1] {
+			t.Fatalf("Ascend visited %d after %d", got[i], got[i-1])
+		}
+	}
+	i := 0
+	for k := 0; k < 100; k += 2 {
+		for i < len(got) && got[i] < k {
+			i++
+		}
+		if i == len(got) || got[i] != k {
+			t.Errorf("Ascend did not visit %d", k)
+		}
+	}
+}
+
+// TestSyncTreeDoReentrant tests that the function passed to Do
+// may call other SyncTree methods, including ones that modify
+// the tree, while another goroutine is writing to the tree.
+func TestSyncTreeDoReentrant(t *testing.T) {
+	tree := NewSyncOrdered[int, int]()
+	for k := 0; k < 100; k++ {
+		tree.Add(k, k)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for k := 100; k < 1000; k++ {
+			tree.Add(k, k)
+		}
+	}()
+	n := 0
+	tree.Do(func(k, _ int) {
+		if v, ok := tree.Find(k); !ok || v != k {
+			t.Errorf("Find(%d)=%d, %t, expected %d, true", k, v, ok, k)
+		}
+		tree.Add(-k-1, k)
+		n++
+	})
+	<-done
+	if n < 100 {
+		t.Errorf("Do visited %d keys, expected at least 100", n)
+	}
+	if got, want := tree.Len(), 1000+n; got != want {
+		t.Errorf("Len()=%d, expected %d", got, want)
+	}
+}
+
+// Run with -race.
+func TestSyncTreeConcurrent(t *testing.T) {
+	const (
+		n       = 1000
+		workers = 8
+	)
+	tree := NewSyncOrdered[int, int]()
+	for k := 0; k < n; k += 2 {
+		tree.Add(k, k)
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(w)))
+			for i := 0; i < 500; i++ {
+				// The odd keys come and go; the even keys stay.
+				k := 2*rng.Intn(n/2) + 1
+				switch i % 5 {
+				case 0:
+					tree.Add(k, k)
+				case 1:
+					tree.Remove(k)
+				case 2:
+					if v, ok := tree.Find(k - 1); !ok || v != k-1 {
+						t.Errorf("Find(%d)=%d, %t, expected %d, true", k-1, v, ok, k-1)
+					}
+				case 3:
+					prev, evens := -1, 0
+					tree.Do(func(k, _ int) {
+						if k < prev {
+							t.Errorf("Do visited %d after %d", k, prev)
+						}
+						if k%2 == 0 {
+							evens++
+						}
+						prev = k
+					})
+					if evens != n/2 {
+						t.Errorf("Do visited %d even keys, expected %d", evens, n/2)
+					}
+				case 4:
+					evens := 0
+					for it := tree.Iterator(); it.Valid(); it.Next() {
+						if it.Key()%2 == 0 {
+							evens++
+						}
+					}
+					if evens != n/2 {
+						t.Errorf("Iterator visited %d even keys, expected %d", evens, n/2)
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	ensureInvariants(t, tree.tree)
+}
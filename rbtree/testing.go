@@ -5,15 +5,40 @@
 package rbtree
 
 import (
+	"cmp"
 	"fmt"
 	"testing"
 )
 
-func emptyNode() *Node {
-	n := new(Node)
-	n.left = &nilNode
-	n.right = &nilNode
-	n.parent = &nilNode
+func emptyNode[K, V any](tree *Tree[K, V]) *TreeNode[K, V] {
+	n := new(TreeNode[K, V])
+	n.left = tree.nilNode
+	n.right = tree.nilNode
+	n.parent = tree.nilNode
+	return n
+}
+
+// setRoot makes n the root of the tree.  The tree rooted at n may
+// use nil for leaves; they are replaced by the tree's sentinel, and
+// the parent pointers and sizes of all nodes are set.
+func setRoot[K, V any](tree *Tree[K, V], n *TreeNode[K, V]) {
+	tree.root = fillNils(tree, n)
+	tree.root.parent = tree.nilNode
+}
+
+func fillNils[K, V any](tree *Tree[K, V], n *TreeNode[K, V]) *TreeNode[K, V] {
+	if n == nil || n.isNil() {
+		return tree.nilNode
+	}
+	n.left = fillNils(tree, n.left)
+	if !n.left.isNil() {
+		n.left.parent = n
+	}
+	n.right = fillNils(tree, n.right)
+	if !n.right.isNil() {
+		n.right.parent = n
+	}
+	n.size = n.left.size + n.right.size + 1
 	return n
 }
 
@@ -41,17 +66,21 @@ func (a strKey) Compare(bKey Key) int {
 	return 0
 }
 
-func ensureInvariants(t *testing.T, tree *RbTree) {
+func ensureInvariants[K, V any](t *testing.T, tree *Tree[K, V]) {
 	if tree.root.color != black {
 		t.Errorf("Root node is not colored black")
 	}
 	redOrBlack(t, tree.root)
 	redsFollowedByBlacks(t, tree.root)
 	sameBlackHeight(t, tree.root)
+	if tree.nilNode.size != 0 {
+		t.Errorf("nilNode has a non-zero size")
+	}
+	correctSizes(t, tree.root)
 }
 
-func redOrBlack(t *testing.T, n *Node) {
-	if n == &nilNode {
+func redOrBlack[K, V any](t *testing.T, n *TreeNode[K, V]) {
+	if n.isNil() {
 		if n.color != black {
 			t.Errorf("nilNode is not black")
 		}
@@ -64,8 +93,8 @@ func redOrBlack(t *testing.T, n *Node) {
 	redOrBlack(t, n.right)
 }
 
-func redsFollowedByBlacks(t *testing.T, n *Node) {
-	if n == &nilNode {
+func redsFollowedByBlacks[K, V any](t *testing.T, n *TreeNode[K, V]) {
+	if n.isNil() {
 		return
 	}
 	if n.color == red {
@@ -77,8 +106,8 @@ func redsFollowedByBlacks(t *testing.T, n *Node) {
 	redsFollowedByBlacks(t, n.right)
 }
 
-func sameBlackHeight(t *testing.T, n *Node) int {
-	if n == &nilNode {
+func sameBlackHeight[K, V any](t *testing.T, n *TreeNode[K, V]) int {
+	if n.isNil() {
 		return 1
 	}
 
@@ -94,11 +123,91 @@ func sameBlackHeight(t *testing.T, n *Node) int {
 	return blkHt
 }
 
-func dump(lvl int, n *Node) {
+func correctSizes[K, V any](t *testing.T, n *TreeNode[K, V]) int {
+	if n.isNil() {
+		return 0
+	}
+	sz := correctSizes(t, n.left) + correctSizes(t, n.right) + 1
+	if n.size != sz {
+		t.Errorf("Node %v has size %d, but its subtree has %d nodes", n.Key, n.size, sz)
+	}
+	return sz
+}
+
+func ensureIntervalInvariants[T cmp.Ordered, V any](t *testing.T, tree *IntervalTree[T, V]) {
+	ensureInvariants(t, tree.tree)
+	correctMaxes(t, tree.tree.root)
+}
+
+func correctMaxes[T cmp.Ordered, V any](t *testing.T, n *TreeNode[Interval[T], intervalValue[T, V]]) (T, bool) {
+	if n.isNil() {
+		var m T
+		return m, false
+	}
+	m := n.Key.Hi
+	if l, ok := correctMaxes(t, n.left); ok {
+		m = max(m, l)
+	}
+	if r, ok := correctMaxes(t, n.right); ok {
+		m = max(m, r)
+	}
+	if n.Value.max != m {
+		t.Errorf("Node %v has max %v, but its subtree's max is %v", n.Key, n.Value.max, m)
+	}
+	return m, true
+}
+
+func ensurePersistentInvariants[K, V any](t *testing.T, tree *Persistent[K, V]) {
+	if isRed(tree.root) {
+		t.Errorf("Root node is not colored black")
+	}
+	pBlackHeight(t, tree.root)
+	if n := pCheck(t, tree, tree.root, nil, nil); n != tree.Len() {
+		t.Errorf("Tree has %d nodes, but its Len is %d", n, tree.Len())
+	}
+}
+
+// pBlackHeight returns the black-height of the subtree rooted at n,
+// checking that it is the same on every path and that red nodes
+// lean left and are followed by black nodes.
+func pBlackHeight[K, V any](t *testing.T, n *pnode[K, V]) int {
+	if n == nil {
+		return 1
+	}
+	if isRed(n.right) {
+		t.Errorf("Red node %v is a right child", n.right.key)
+	}
+	if isRed(n) && isRed(n.left) {
+		t.Errorf("Red node not followed by black nodes")
+	}
+	l := pBlackHeight(t, n.left)
+	r := pBlackHeight(t, n.right)
+	if l != r {
+		t.Errorf("Unbalanced black-height")
+	}
+	if n.color == black {
+		l += 1
+	}
+	return l
+}
+
+// pCheck returns the number of nodes in the subtree rooted at n,
+// checking that its keys are within [lo, hi].
+func pCheck[K, V any](t *testing.T, tree *Persistent[K, V], n *pnode[K, V], lo, hi *K) int {
+	if n == nil {
+		return 0
+	}
+	if lo != nil && tree.cmp(n.key, *lo) < 0 || hi != nil && tree.cmp(n.key, *hi) > 0 {
+		t.Errorf("Node %v is out of order", n.key)
+	}
+	return pCheck(t, tree, n.left, lo, &n.key) + pCheck(t, tree, n.right, &n.key, hi) + 1
+}
+
+func dump[K, V any](lvl int, n *TreeNode[K, V]) {
 	for i := 0; i < lvl; i += 1 {
 		fmt.Printf(" ")
 	}
-	if n == &nilNode {
+	if n.isNil() {
 		fmt.Printf("<nil>, black\n")
 		return
 	}
@@ -111,21 +220,20 @@ func dump(lvl int, n *Node) {
 	dump(lvl+1, n.right)
 }
 
-func reflect(n *Node) *Node {
-	if n == &nilNode {
+func reflect[K, V any](n *TreeNode[K, V]) *TreeNode[K, V] {
+	if n.isNil() {
 		return n
 	}
-	m := newNode(n.Key, n.Value)
-	m.color = n.color
+	m := &TreeNode[K, V]{Key: n.Key, Value: n.Value, color: n.color, size: n.size}
 	m.left = reflect(n.right)
 	m.right = reflect(n.left)
 	m.left.parent = m
 	m.right.parent = m
-	m.parent = &nilNode
+	m.parent = n.parent
 	return m
 }
 
-func followPath(i int, s string, n *Node, reflect bool) *Node {
+func followPath[K, V any](i int, s string, n *TreeNode[K, V], reflect bool) *TreeNode[K, V] {
 	if i == len(s) {
 		return n
 	}
@@ -145,19 +253,19 @@ func followPath(i int, s string, n *Node, reflect bool) *Node {
 	return nil
 }
 
-func path(s string, tree *RbTree, reflect bool) *Node {
+func path[K, V any](s string, tree *Tree[K, V], reflect bool) *TreeNode[K, V] {
 	return followPath(0, s, tree.root, reflect)
 }
 
-func countNodes(n *Node) int {
-	if n == &nilNode {
+func countNodes[K, V any](n *TreeNode[K, V]) int {
+	if n.isNil() {
 		return 0
 	}
 	return 1 + countNodes(n.left) + countNodes(n.right)
 }
 
-func height(n *Node) int {
-	if n == &nilNode {
+func height[K, V any](n *TreeNode[K, V]) int {
+	if n.isNil() {
 		return 0
 	}
 	l := height(n.left)
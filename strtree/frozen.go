@@ -0,0 +1,366 @@
+package strtree
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"unicode/utf8"
+)
+
+// The frozen form of a set is a flat, pointer-free layout that can
+// be read in place, for example from a memory-mapped file.  It is
+// a header followed by the nodes of the tree in depth-first order.
+//
+// The header is:
+//	magic   [4]byte  "STR\x00"
+//	version uint8    frozenVersion
+//	flags   uint8    flagRunes if the set is in rune mode
+//	count   uint32   the number of strings in the set
+//
+// Each node is:
+//	flags   uint8    flagMember if the node's string is in the set
+//	size    uvarint  the number of bytes of the prefix
+//	kids    uvarint  the number of kids
+//	prefix  [size]byte
+//	offsets [kids]uint32  the offset of each kid from the start
+//
+// The kids are sorted by their first unit, as in a T, and each kid
+// immediately follows the subtree of the one before it; the first
+// kid immediately follows its parent.  Integers are little-endian.
+
+const (
+	frozenMagic   = "STR\x00"
+	frozenVersion = 1
+	headerSize    = len(frozenMagic) + 2 + 4
+
+	flagRunes  = 1 << 0
+	flagMember = 1 << 0
+
+	// MaxFrozenDepth is the maximum depth of a frozen tree,
+	// which bounds the memory used by Open to check it and
+	// the stack used to walk it.  Each level below the root
+	// adds at least one unit to the strings beneath it, so
+	// only a set with strings this long can be too deep.
+	maxFrozenDepth = 1 << 16
+)
+
+// ErrFormat is returned by Open for data that is
+// not in the format written by Freeze.
+var ErrFormat = errors.New("strtree: invalid frozen format")
+
+// ErrTooLarge is returned by Freeze if the layout of the set
+// would be larger than 4GiB or nested too deeply.
+var ErrTooLarge = errors.New("strtree: frozen set is too large")
+
+// Freeze returns the set in a flat, pointer-free layout that can be
+// read in place by Open.  Offsets and counts in the layout are 32 bits,
+// so Freeze returns ErrTooLarge if the layout would be larger than 4GiB.
+// It also returns ErrTooLarge if the tree is more than 65536 levels
+// deep, which can only happen if the set has strings at least that long.
+func (t *T) Freeze() ([]byte, error) {
+	return t.freeze(math.MaxUint32)
+}
+
+// freeze returns the frozen layout of the set, or ErrTooLarge
+// if any offset or count in it would be greater than max.
+func (t *T) freeze(max uint64) ([]byte, error) {
+	if uint64(t.Len()) > max {
+		return nil, ErrTooLarge
+	}
+	var flags byte
+	if t.runes {
+		flags |= flagRunes
+	}
+	buf := append([]byte(frozenMagic), frozenVersion, flags)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(t.Len()))
+	buf, err := freeze(buf, t.m(), max, 1)
+	if err == nil && uint64(len(buf)) > max {
+		err = ErrTooLarge
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// freeze appends the subtree rooted at t, at the given depth, to buf,
+// or returns ErrTooLarge if the offset of any kid would be greater
+// than max or the subtree would be deeper than maxFrozenDepth.
+func freeze(buf []byte, t *Map[struct{}], max uint64, depth int) ([]byte, error) {
+	if depth > maxFrozenDepth {
+		return nil, ErrTooLarge
+	}
+	var flags byte
+	if t.mem {
+		flags |= flagMember
+	}
+	buf = append(buf, flags)
+	buf = binary.AppendUvarint(buf, uint64(len(t.prefix)))
+	buf = binary.AppendUvarint(buf, uint64(len(t.kids)))
+	buf = append(buf, t.prefix...)
+	offs := len(buf)
+	buf = append(buf, make([]byte, 4*len(t.kids))...)
+	for i := range t.kids {
+		if uint64(len(buf)) > max {
+			return nil, ErrTooLarge
+		}
+		binary.LittleEndian.PutUint32(buf[offs+4*i:], uint32(len(buf)))
+		var err error
+		if buf, err = freeze(buf, &t.kids[i], max, depth+1); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+// A Frozen is a read-only set of strings that is read in place
+// from the layout written by Freeze.  Its strings are visited in
+// the same lexicographical order as those of a T.
+type Frozen struct {
+	data  []byte
+	runes bool
+	len   int
+}
+
+// frozenNode is a node decoded from the frozen layout.
+type frozenNode struct {
+	mem    bool
+	prefix []byte
+	kids   []byte // The little-endian offsets of the kids
+}
+
+func (n *frozenNode) nkids() int {
+	return len(n.kids) / 4
+}
+
+// Open returns the Frozen set in data, which must be in the format
+// written by Freeze, or ErrFormat if it is not.  The data is read in
+// place and must not be modified while the set is in use.  Open
+// checks every node of the layout, so this operation is O(n) in the
+// size of the data.  It checks the nodes iteratively, using memory
+// proportional to the depth of the tree, which is bounded.
+func Open(data []byte) (*Frozen, error) {
+	if len(data) < headerSize || string(data[:len(frozenMagic)]) != frozenMagic {
+		return nil, ErrFormat
+	}
+	if data[len(frozenMagic)] != frozenVersion {
+		return nil, ErrFormat
+	}
+	flags := data[len(frozenMagic)+1]
+	if flags&^flagRunes != 0 {
+		return nil, ErrFormat
+	}
+	f := &Frozen{
+		data:  data,
+		runes: flags&flagRunes != 0,
+		len:   int(binary.LittleEndian.Uint32(data[len(frozenMagic)+2:])),
+	}
+	end, count, err := f.check()
+	if err != nil {
+		return nil, err
+	}
+	if end != len(data) || count != f.len {
+		return nil, ErrFormat
+	}
+	return f, nil
+}
+
+// node decodes the node at the given offset, returning it, the
+// offset just past it, and false if it is not a valid node.
+func (f *Frozen) node(off int) (frozenNode, int, bool) {
+	data := f.data
+	if off >= len(data) || data[off]&^flagMember != 0 {
+		return frozenNode{}, 0, false
+	}
+	n := frozenNode{mem: data[off]&flagMember != 0}
+	off++
+	size, w := binary.Uvarint(data[off:])
+	if w <= 0 {
+		return frozenNode{}, 0, false
+	}
+	off += w
+	kids, w := binary.Uvarint(data[off:])
+	if w <= 0 {
+		return frozenNode{}, 0, false
+	}
+	off += w
+	if size > uint64(len(data)-off) || kids > uint64(len(data)-off-int(size))/4 {
+		return frozenNode{}, 0, false
+	}
+	n.prefix = data[off : off+int(size)]
+	off += int(size)
+	n.kids = data[off : off+4*int(kids)]
+	return n, off + 4*int(kids), true
+}
+
+// kid returns the ith kid of a node.  The data must
+// already have been checked.
+func (f *Frozen) kid(n *frozenNode, i int) frozenNode {
+	k, _, _ := f.node(int(binary.LittleEndian.Uint32(n.kids[4*i:])))
+	return k
+}
+
+// check checks every node of the layout, returning the offset just
+// past the last node and the number of strings in the set.  Since each
+// kid must follow the subtree of the one before it, the nodes are
+// checked in order, once each, and only the path from the root to the
+// current node is kept, which is at most maxFrozenDepth nodes long.
+func (f *Frozen) check() (int, int, error) {
+	type frame struct {
+		n    frozenNode
+		next int    // The index of the next kid to check
+		prev []byte // The prefix of the previous kid
+	}
+	root, end, ok := f.node(headerSize)
+	if !ok {
+		return 0, 0, ErrFormat
+	}
+	count := 0
+	if root.mem {
+		count++
+	}
+	path := []frame{{n: root}}
+	for len(path) > 0 {
+		top := &path[len(path)-1]
+		if top.next == top.n.nkids() {
+			path = path[:len(path)-1]
+			continue
+		}
+		if int(binary.LittleEndian.Uint32(top.n.kids[4*top.next:])) != end {
+			return 0, 0, ErrFormat
+		}
+		k, kend, ok := f.node(end)
+		if !ok || len(k.prefix) == 0 {
+			return 0, 0, ErrFormat
+		}
+		if top.next > 0 && compareUnits(top.prev, k.prefix, f.runes) >= 0 {
+			return 0, 0, ErrFormat
+		}
+		top.prev = k.prefix
+		top.next++
+		if k.mem {
+			count++
+		}
+		end = kend
+		if len(path) == maxFrozenDepth {
+			return 0, 0, ErrFormat
+		}
+		path = append(path, frame{n: k})
+	}
+	return end, count, nil
+}
+
+func (f *Frozen) root() frozenNode {
+	n, _, _ := f.node(headerSize)
+	return n
+}
+
+// frozenSearch returns the index of the kid of a node with the same
+// first unit as s, or -1 if there is no such kid.
+func frozenSearch[S string | []byte](f *Frozen, n *frozenNode, s S) int {
+	lo, hi := 0, n.nkids()
+	for lo < hi {
+		m := int(uint(lo+hi) >> 1)
+		k := f.kid(n, m)
+		switch c := compareUnits(k.prefix, s, f.runes); {
+		case c < 0:
+			lo = m + 1
+		case c > 0:
+			hi = m
+		default:
+			return m
+		}
+	}
+	return -1
+}
+
+// Len returns the number of strings in the set.  This operation
+// is constant in the number of entries.
+func (f *Frozen) Len() int {
+	return f.len
+}
+
+// Member returns true if the string is a member of the set
+// and false otherwise.
+func (f *Frozen) Member(s string) bool {
+	return member(f, s)
+}
+
+// MemberBytes returns true if the string of a byte slice is a
+// member of the set and false otherwise.  It does not convert the
+// byte slice to a string.
+func (f *Frozen) MemberBytes(b []byte) bool {
+	return member(f, b)
+}
+
+func member[S string | []byte](f *Frozen, s S) bool {
+	n := f.root()
+	for {
+		if !hasPrefix(s, n.prefix) {
+			return false
+		}
+		s = s[len(n.prefix):]
+		if len(s) == 0 {
+			return n.mem
+		}
+		i := frozenSearch(f, &n, s)
+		if i < 0 {
+			return false
+		}
+		n = f.kid(&n, i)
+	}
+}
+
+// WithPrefix calls a function on every string in the set that
+// begins with the given prefix in lexicographical order.  Only
+// the subtree of strings with the prefix is walked.
+func (f *Frozen) WithPrefix(p string, fn func(string)) {
+	var str []byte
+	n := f.root()
+	for {
+		if hasPrefix(n.prefix, p) {
+			f.walk(&n, str, fn)
+			return
+		}
+		if !hasPrefix(p, n.prefix) {
+			return
+		}
+		str = append(str, n.prefix...)
+		p = p[len(n.prefix):]
+		i := frozenSearch(f, &n, p)
+		if i >= 0 {
+			n = f.kid(&n, i)
+			continue
+		}
+		// In rune mode, p may end in the middle of
+		// the first rune of some of the kids.
+		if f.runes && len(p) < utf8.UTFMax {
+			for i := 0; i < n.nkids(); i++ {
+				if k := f.kid(&n, i); hasPrefix(k.prefix, p) {
+					f.walk(&k, str, fn)
+				}
+			}
+		}
+		return
+	}
+}
+
+// Iterate calls a function on every string in the set in
+// lexicographical order.
+func (f *Frozen) Iterate(fn func(string)) {
+	n := f.root()
+	f.walk(&n, nil, fn)
+}
+
+// walk walks the subtree rooted at n in lexicographical order and
+// calls a function on every string in it prefixed by p.
+func (f *Frozen) walk(n *frozenNode, p []byte, fn func(string)) {
+	str := append(p, n.prefix...)
+	if n.mem {
+		fn(string(str))
+	}
+	for i := 0; i < n.nkids(); i++ {
+		k := f.kid(n, i)
+		f.walk(&k, str, fn)
+	}
+}
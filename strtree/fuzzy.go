@@ -0,0 +1,101 @@
+package strtree
+
+// Fuzzy calls a function on every string in the set that is within
+// maxEdits edits of s, and its Levenshtein distance from s, in
+// lexicographical order.  An edit is the insertion, deletion or
+// substitution of a single byte or, in rune mode, of a single rune.
+func (t *T) Fuzzy(s string, maxEdits int, f func(string, int)) {
+	t.m().Fuzzy(s, maxEdits, func(str string, _ struct{}, d int) { f(str, d) })
+}
+
+// Fuzzy calls a function on every string in the map that is within
+// maxEdits edits of s, its value, and its Levenshtein distance from s,
+// in lexicographical order.  An edit is the insertion, deletion or
+// substitution of a single byte or, in rune mode, of a single rune.
+//
+// The tree is walked depth-first, computing one row of the edit
+// distance table for each byte (or rune) along the path.  A subtree
+// is pruned as soon as every entry of the row exceeds maxEdits, since
+// the distance from s to any string in the subtree can only be greater.
+func (t *Map[V]) Fuzzy(s string, maxEdits int, f func(string, V, int)) {
+	if maxEdits < 0 {
+		return
+	}
+	z := fuzzer[V]{max: maxEdits, f: f}
+	if t.runes {
+		z.s = []rune(s)
+	} else {
+		z.s = make([]rune, len(s))
+		for i := 0; i < len(s); i++ {
+			z.s[i] = rune(s[i])
+		}
+	}
+	// The first row is the distance from each prefix of s
+	// to the empty string.
+	for i := 0; i <= len(z.s); i++ {
+		z.rows = append(z.rows, i)
+	}
+	z.walk(t, "")
+}
+
+type fuzzer[V any] struct {
+	// s is the string, as bytes or runes.
+	s   []rune
+	max int
+	f   func(string, V, int)
+
+	// rows is a stack of rows of the edit distance table, each
+	// of length len(s)+1.  The top row holds the distances from
+	// each prefix of s to the string walked so far.
+	rows []int
+}
+
+// walk walks the tree rooted at t, where p is the string
+// walked so far.
+func (z *fuzzer[V]) walk(t *Map[V], p string) {
+	base := len(z.rows)
+	defer func() { z.rows = z.rows[:base] }()
+
+	if t.runes {
+		for _, r := range t.prefix {
+			if !z.push(r) {
+				return
+			}
+		}
+	} else {
+		for i := 0; i < len(t.prefix); i++ {
+			if !z.push(rune(t.prefix[i])) {
+				return
+			}
+		}
+	}
+	str := p + t.prefix
+	if d := z.rows[len(z.rows)-1]; t.mem && d <= z.max {
+		z.f(str, t.val, d)
+	}
+	for i := range t.kids {
+		z.walk(&t.kids[i], str)
+	}
+}
+
+// push pushes the row for the string walked so far followed by
+// c.  It returns false if every entry of the row is greater than
+// the maximum number of edits.
+func (z *fuzzer[V]) push(c rune) bool {
+	w := len(z.s) + 1
+	prev := len(z.rows) - w
+	z.rows = append(z.rows, z.rows[prev:]...)
+	old, cur := z.rows[prev:prev+w], z.rows[prev+w:]
+
+	cur[0] = old[0] + 1
+	least := cur[0]
+	for j := 1; j < w; j++ {
+		sub := old[j-1]
+		if z.s[j-1] != c {
+			sub++
+		}
+		cur[j] = min(sub, min(old[j], cur[j-1])+1)
+		least = min(least, cur[j])
+	}
+	return least <= z.max
+}
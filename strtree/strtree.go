@@ -1,114 +1,382 @@
 // strtree implements a radix tree on strings
+//
+// The strings of a tree are always visited in lexicographical
+// order: the order of Go's string comparison operators, which
+// compare strings byte-wise.  For valid UTF-8, this is also the
+// order of the strings' code points.
+//
+// By default, the edges of a tree are split at any byte, so an
+// edge may end in the middle of a UTF-8 sequence.  A tree in rune
+// mode, set by SplitRunes, only splits its edges between runes.
+// Rune mode assumes that the strings are valid UTF-8.
 package strtree
 
 import (
 	"sort"
 	"strings"
+	"unicode/utf8"
 )
 
 // A T is the root of a radix tree.  Each T represents a set
 // of strings all with a common (possibly empty) prefix.
 // The zero-value is ready to use.
-type T struct {
-	prefix string // The string prefix represented by this node
-	kids   []T    // Trees containing extensions of this prefix
-	mem    bool   // True if this string is in the set, otherwise false
+//
+// A T is a Map with no values.
+type T Map[struct{}]
+
+// A Map is the root of a radix tree that maps strings to values.
+// Each Map represents a map from strings all with a common
+// (possibly empty) prefix.  The zero-value is ready to use.
+type Map[V any] struct {
+	prefix string   // The string prefix represented by this node
+	kids   []Map[V] // Trees containing extensions of this prefix
+	mem    bool     // True if this string is in the map, otherwise false
+	runes  bool     // True if edges are only split between runes
+	val    V        // The value of this string if mem is true
+}
+
+func (t *T) m() *Map[struct{}] {
+	return (*Map[struct{}])(t)
+}
+
+// SplitRunes puts the set in rune mode, in which edges are only
+// split between runes.  It panics if the set is not empty.
+func (t *T) SplitRunes() {
+	t.m().SplitRunes()
 }
 
 // Insert inserts a string into the set
 func (t *T) Insert(s string) {
-	if s == t.prefix || (len(t.prefix) == 0 && t.mem == false) {
-		t.prefix = s
+	t.m().Put(s, struct{}{})
+}
+
+// InsertBytes inserts the string of a byte slice into the set.
+// Only the part of the string that is not already in the tree
+// is converted to a string.
+func (t *T) InsertBytes(b []byte) {
+	t.m().PutBytes(b, struct{}{})
+}
+
+// Member returns true if the string is a member of the set
+// and false otherwise.
+func (t *T) Member(s string) bool {
+	_, ok := t.m().Get(s)
+	return ok
+}
+
+// MemberBytes returns true if the string of a byte slice is a
+// member of the set and false otherwise.  It does not convert the
+// byte slice to a string.
+func (t *T) MemberBytes(b []byte) bool {
+	_, ok := t.m().GetBytes(b)
+	return ok
+}
+
+// Delete removes a string from the set, returning true if it
+// was a member and false otherwise.  A node that is left with
+// no member string and a single child is merged with the child.
+func (t *T) Delete(s string) bool {
+	return t.m().Delete(s)
+}
+
+// LongestPrefixOf returns the longest string in the set that is
+// a prefix of s, and true, or the empty string and false if no
+// string in the set is a prefix of s.
+func (t *T) LongestPrefixOf(s string) (string, bool) {
+	p, _, ok := t.m().LongestPrefixOf(s)
+	return p, ok
+}
+
+// WithPrefix calls a function on every string in the set that
+// begins with the given prefix in lexicographical order.  Only
+// the subtree of strings with the prefix is walked.
+func (t *T) WithPrefix(p string, f func(string)) {
+	t.m().WithPrefix(p, func(s string, _ struct{}) { f(s) })
+}
+
+// Iterate calls a function on every string in the set in
+// lexicographical order.
+func (t *T) Iterate(f func(string)) {
+	t.m().Iterate(func(s string, _ struct{}) { f(s) })
+}
+
+// Len returns the number of strings in the set.  This operation
+// is O(n) in the number of entries.
+func (t *T) Len() int {
+	return t.m().Len()
+}
+
+// SplitRunes puts the map in rune mode, in which edges are only
+// split between runes.  It panics if the map is not empty.
+func (t *Map[V]) SplitRunes() {
+	if t.mem || len(t.kids) > 0 {
+		panic("strtree: SplitRunes called on a non-empty tree")
+	}
+	t.runes = true
+}
+
+// Put maps a string to a value, replacing the string's
+// previous value if it is already in the map.
+func (t *Map[V]) Put(s string, v V) {
+	put(t, s, v)
+}
+
+// PutBytes maps the string of a byte slice to a value, replacing
+// the string's previous value if it is already in the map.  Only
+// the part of the string that is not already in the tree is
+// converted to a string.
+func (t *Map[V]) PutBytes(b []byte, v V) {
+	put(t, b, v)
+}
+
+func put[V any, S string | []byte](t *Map[V], s S, v V) {
+	if equal(s, t.prefix) || (len(t.prefix) == 0 && t.mem == false && len(t.kids) == 0) {
+		if !equal(s, t.prefix) {
+			t.prefix = string(s)
+		}
 		t.mem = true
+		t.val = v
 		return
 	}
 
-	if strings.HasPrefix(s, t.prefix) {
+	if hasPrefix(s, t.prefix) {
 		s = s[len(t.prefix):]
-		i := search(t.kids, s)
+		i := search(t.kids, s, t.runes)
 		if i < 0 {
-			t.kids = insert(t.kids, T{prefix: s, mem: true})
+			kid := Map[V]{prefix: string(s), mem: true, runes: t.runes, val: v}
+			t.kids = insert(t.kids, kid, t.runes)
 		} else {
-			t.kids[i].Insert(s)
+			put(&t.kids[i], s, v)
 		}
 		return
 	}
 
-	c := commonPrefix(t.prefix, s)
-	suffix := T{prefix: t.prefix[len(c):], kids: t.kids, mem: t.mem}
-	t.prefix = c
-	t.kids = []T{suffix}
-	if s == c {
+	n := commonLen(t.prefix, s, t.runes)
+	suffix := Map[V]{prefix: t.prefix[n:], kids: t.kids, mem: t.mem, runes: t.runes, val: t.val}
+	t.prefix = t.prefix[:n]
+	t.kids = []Map[V]{suffix}
+	if len(s) == n {
 		t.mem = true
+		t.val = v
 		return
 	}
-	t.kids = insert(t.kids, T{prefix: s[len(c):], mem: true})
+	kid := Map[V]{prefix: string(s[n:]), mem: true, runes: t.runes, val: v}
+	t.kids = insert(t.kids, kid, t.runes)
 	t.mem = false
+	var zero V
+	t.val = zero
 }
 
 // commonPrefix returns the common prefix of two strings.
 // This may be the empty string.
 func commonPrefix(a, b string) string {
-	var c []byte
+	return a[:commonLen(a, b, false)]
+}
+
+// commonLen returns the length of the common prefix of two
+// strings.  If runes is true, then the common prefix only
+// includes whole runes.
+func commonLen[S string | []byte](a string, b S, runes bool) int {
 	len := minLen(a, b)
-	for i := 0; i < len; i++ {
-		if a[i] != b[i] {
-			break
+	i := 0
+	for i < len && a[i] == b[i] {
+		i++
+	}
+	if runes {
+		for i > 0 && i < len && !utf8.RuneStart(a[i]) {
+			i--
 		}
-		c = append(c, a[i])
 	}
-	return string(c)
+	return i
 }
 
 // minLen returns the length of the smaller of two strings.
-func minLen(a, b string) int {
+func minLen[S string | []byte](a string, b S) int {
 	if len(a) < len(b) {
 		return len(a)
 	}
 	return len(b)
 }
 
-// Member returns true if the string is a member of the set
-// and false otherwise.
-func (t *T) Member(s string) bool {
-	if s == t.prefix {
-		return t.mem
+// hasPrefix returns true if s begins with the prefix p.
+func hasPrefix[S, P string | []byte](s S, p P) bool {
+	if len(s) < len(p) {
+		return false
+	}
+	for i := 0; i < len(p); i++ {
+		if s[i] != p[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// equal returns true if s and p are the same string.
+func equal[S, P string | []byte](s S, p P) bool {
+	return len(s) == len(p) && hasPrefix(s, p)
+}
+
+// Get returns the value of the string and true if the string
+// is in the map, and the zero value and false otherwise.
+func (t *Map[V]) Get(s string) (V, bool) {
+	return get(t, s)
+}
+
+// GetBytes returns the value of the string of a byte slice
+// and true if the string is in the map, and the zero value and
+// false otherwise.  It does not convert the byte slice to a string.
+func (t *Map[V]) GetBytes(b []byte) (V, bool) {
+	return get(t, b)
+}
+
+func get[V any, S string | []byte](t *Map[V], s S) (V, bool) {
+	if equal(s, t.prefix) {
+		return t.val, t.mem
 	}
 
-	if strings.HasPrefix(s, t.prefix) {
+	if hasPrefix(s, t.prefix) {
 		s = s[len(t.prefix):]
-		i := search(t.kids, s)
-		if i < 0 {
+		i := search(t.kids, s, t.runes)
+		if i >= 0 {
+			return get(&t.kids[i], s)
+		}
+	}
+
+	var zero V
+	return zero, false
+}
+
+// Delete removes a string from the map, returning true if it
+// was in the map and false otherwise.  A node that is left with
+// no member string and a single child is merged with the child.
+func (t *Map[V]) Delete(s string) bool {
+	if !strings.HasPrefix(s, t.prefix) {
+		return false
+	}
+	if s == t.prefix {
+		if !t.mem {
 			return false
 		}
-		return t.kids[i].Member(s)
+		t.mem = false
+		var zero V
+		t.val = zero
+		t.compact()
+		return true
+	}
+
+	s = s[len(t.prefix):]
+	i := search(t.kids, s, t.runes)
+	if i < 0 || !t.kids[i].Delete(s) {
+		return false
+	}
+	if !t.kids[i].mem && len(t.kids[i].kids) == 0 {
+		t.kids = append(t.kids[:i], t.kids[i+1:]...)
 	}
+	t.compact()
+	return true
+}
 
-	return false
+// compact restores the invariant that a node that is not a
+// member has more than one child.  A node with a single child
+// is merged with it, and a node with no children is emptied.
+func (t *Map[V]) compact() {
+	if t.mem {
+		return
+	}
+	switch len(t.kids) {
+	case 0:
+		*t = Map[V]{runes: t.runes}
+	case 1:
+		kid := t.kids[0]
+		t.prefix += kid.prefix
+		t.kids = kid.kids
+		t.mem = kid.mem
+		t.val = kid.val
+	}
 }
 
-// Iterate calls a function on every string in the set in
-// lexicographical order.
-func (t *T) Iterate(f func(string)) {
+// LongestPrefixOf returns the longest string in the map that is
+// a prefix of s, its value, and true, or the empty string, the
+// zero value and false if no string in the map is a prefix of s.
+func (t *Map[V]) LongestPrefixOf(s string) (string, V, bool) {
+	var val V
+	longest, ok := "", false
+	n := 0 // The length of the prefix of s matched so far
+	for {
+		if !strings.HasPrefix(s[n:], t.prefix) {
+			break
+		}
+		n += len(t.prefix)
+		if t.mem {
+			longest, val, ok = s[:n], t.val, true
+		}
+		if n == len(s) {
+			break
+		}
+		i := search(t.kids, s[n:], t.runes)
+		if i < 0 {
+			break
+		}
+		t = &t.kids[i]
+	}
+	return longest, val, ok
+}
+
+// WithPrefix calls a function on every string in the map that
+// begins with the given prefix, and its value, in lexicographical
+// order.  Only the subtree of strings with the prefix is walked.
+func (t *Map[V]) WithPrefix(p string, f func(string, V)) {
+	str := ""
+	for {
+		if strings.HasPrefix(t.prefix, p) {
+			t.walk(str, f)
+			return
+		}
+		if !strings.HasPrefix(p, t.prefix) {
+			return
+		}
+		str += t.prefix
+		p = p[len(t.prefix):]
+		i := search(t.kids, p, t.runes)
+		if i >= 0 {
+			t = &t.kids[i]
+			continue
+		}
+		// In rune mode, p may end in the middle of
+		// the first rune of some of the kids.
+		if t.runes && len(p) < utf8.UTFMax {
+			for i := range t.kids {
+				if strings.HasPrefix(t.kids[i].prefix, p) {
+					t.kids[i].walk(str, f)
+				}
+			}
+		}
+		return
+	}
+}
+
+// Iterate calls a function on every string in the map, and
+// its value, in lexicographical order.
+func (t *Map[V]) Iterate(f func(string, V)) {
 	t.walk("", f)
 }
 
 // walk walks the tree in lexicographical order and calls
 // a function on every string in the tree prefixed by the
 // string given as a parameter.
-func (t *T) walk(p string, f func(string)) {
+func (t *Map[V]) walk(p string, f func(string, V)) {
 	str := p + t.prefix
 	if t.mem {
-		f(str)
+		f(str, t.val)
 	}
 	for i := range t.kids {
 		t.kids[i].walk(str, f)
 	}
 }
 
-// Len returns the number of strings in the set.  This operation
+// Len returns the number of strings in the map.  This operation
 // is O(n) in the number of entries.
-func (t *T) Len() int {
+func (t *Map[V]) Len() int {
 	n := 0
 	if t.mem {
 		n = 1
@@ -119,23 +387,65 @@ func (t *T) Len() int {
 	return n
 }
 
+// The kids of a node are sorted by the first unit of their
+// prefixes: their first byte or, in rune mode, their first rune.
+// No two kids have the same first unit, and since UTF-8 is
+// prefix-free and sorts in code point order, the kids are in
+// lexicographical order in either mode.
+
+// unitLen returns the length in bytes of the first unit of s.
+func unitLen[S string | []byte](s S, runes bool) int {
+	if !runes || s[0] < utf8.RuneSelf {
+		return 1
+	}
+	var buf [utf8.UTFMax]byte
+	n := copy(buf[:], s)
+	_, w := utf8.DecodeRune(buf[:n])
+	return w
+}
+
+// compareUnits returns the lexicographical comparison of the
+// first units of p and s.
+func compareUnits[P, S string | []byte](p P, s S, runes bool) int {
+	p = p[:unitLen(p, runes)]
+	s = s[:unitLen(s, runes)]
+	for i := 0; i < len(p) && i < len(s); i++ {
+		switch {
+		case p[i] < s[i]:
+			return -1
+		case p[i] > s[i]:
+			return 1
+		}
+	}
+	return len(p) - len(s)
+}
+
 // search returns the index for a string in the sorted
 // slice or -1 if there is no index for that string yet.
-func search(ts []T, s string) int {
+func search[V any, S string | []byte](ts []Map[V], s S, runes bool) int {
+	if !runes {
+		n := sort.Search(len(ts), func(i int) bool {
+			return ts[i].prefix[0] >= s[0]
+		})
+		if n == len(ts) || ts[n].prefix[0] != s[0] {
+			return -1
+		}
+		return n
+	}
 	n := sort.Search(len(ts), func(i int) bool {
-		return ts[i].prefix[0] >= s[0]
+		return compareUnits(ts[i].prefix, s, true) >= 0
 	})
-	if n == len(ts) || ts[n].prefix[0] != s[0] {
+	if n == len(ts) || compareUnits(ts[n].prefix, s, true) != 0 {
 		return -1
 	}
 	return n
 }
 
 // insert inserts a node into the slice in sorted order.
-func insert(ts []T, t T) []T {
+func insert[V any](ts []Map[V], t Map[V], runes bool) []Map[V] {
 	ts = append(ts, t)
 	i := len(ts) - 1
-	for ; i > 0 && ts[i-1].prefix[0] > t.prefix[0]; i-- {
+	for ; i > 0 && compareUnits(ts[i-1].prefix, t.prefix, runes) > 0; i-- {
 		ts[i] = ts[i-1]
 	}
 	ts[i] = t
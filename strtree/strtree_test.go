@@ -2,9 +2,14 @@ package strtree
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/binary"
 	"io"
 	"os"
+	"sort"
+	"strings"
 	"testing"
+	"unicode/utf8"
 )
 
 // TestCommonPrefixFunc tests the commonPrefix function
@@ -342,3 +347,701 @@ func TestInsertAlot(t *testing.T) {
 		}
 	}
 }
+
+// checkCompact fails the test if a node other than the root is
+// not a member and has fewer than two kids, or if the kids of
+// a node are not sorted by the first byte, or in rune mode the
+// first rune, of their prefixes.
+func checkCompact[V any](t *testing.T, tree *Map[V], root bool) {
+	if !root && !tree.mem && len(tree.kids) < 2 {
+		t.Errorf("node [%s] is not a member and has %d kids", tree.prefix, len(tree.kids))
+	}
+	for i := range tree.kids {
+		if len(tree.kids[i].prefix) == 0 {
+			t.Errorf("kid of [%s] has an empty prefix", tree.prefix)
+			continue
+		}
+		if i > 0 && firstUnit(tree.kids[i-1].prefix, tree.runes) >= firstUnit(tree.kids[i].prefix, tree.runes) {
+			t.Errorf("kids of [%s] are out of order", tree.prefix)
+		}
+		checkCompact(t, &tree.kids[i], false)
+	}
+}
+
+var romans = [...]string{
+	"romane",
+	"romanus",
+	"romulus",
+	"rubens",
+	"ruber",
+	"rubicon",
+	"rubicundus",
+}
+
+// TestInsertEmptyRoot inserts into a tree whose root has an
+// empty prefix and is not a member, but has kids.
+func TestInsertEmptyRoot(t *testing.T) {
+	var tree T
+	for _, s := range []string{"a", "b", "c"} {
+		tree.Insert(s)
+	}
+	for _, s := range []string{"a", "b", "c"} {
+		if !tree.Member(s) {
+			t.Errorf("%s is not a member", s)
+		}
+	}
+	if tree.Member("ca") || tree.Len() != 3 {
+		t.Errorf("expected exactly a, b and c to be members")
+	}
+}
+
+func TestDelete(t *testing.T) {
+	var tree T
+	for _, s := range romans {
+		tree.Insert(s)
+	}
+	for _, s := range []string{"r", "rom", "rubicons", "abc", ""} {
+		if tree.Delete(s) {
+			t.Errorf("deleted non-member %s", s)
+		}
+	}
+
+	for i, s := range romans {
+		if !tree.Delete(s) {
+			t.Errorf("failed to delete %s", s)
+		}
+		if tree.Delete(s) {
+			t.Errorf("deleted %s twice", s)
+		}
+		checkCompact(t, tree.m(), true)
+		for j, m := range romans {
+			if tree.Member(m) != (j > i) {
+				t.Errorf("after deleting %s, Member(%s)=%t", s, m, !(j > i))
+			}
+		}
+		if tree.Len() != len(romans)-i-1 {
+			t.Errorf("after deleting %s, Len=%d, expected %d", s, tree.Len(), len(romans)-i-1)
+		}
+	}
+	if tree.prefix != "" || len(tree.kids) != 0 || tree.mem {
+		t.Errorf("tree is not empty after deleting every string")
+	}
+}
+
+// TestDeleteMerge checks that deleting a string merges its node
+// with its only remaining child.
+func TestDeleteMerge(t *testing.T) {
+	var tree T
+	tree.Insert("abc")
+	tree.Insert("abcde")
+	tree.Insert("abcdf")
+	tree.Insert("")
+
+	tree.Delete("abcde")
+	checkCompact(t, tree.m(), true)
+	tree.Delete("abc")
+	checkCompact(t, tree.m(), true)
+	if len(tree.kids) != 1 || tree.kids[0].prefix != "abcdf" {
+		t.Fatalf("expected a single kid abcdf, got %v", tree.kids)
+	}
+	tree.Delete("")
+	if tree.prefix != "abcdf" || !tree.mem || len(tree.kids) != 0 {
+		t.Errorf("expected a root of abcdf, got [%s]", tree.prefix)
+	}
+	tree.Insert("x")
+	if !tree.Member("x") || !tree.Member("abcdf") || tree.Len() != 2 {
+		t.Errorf("insert after delete failed")
+	}
+}
+
+func TestWithPrefix(t *testing.T) {
+	var tree T
+	for _, s := range romans {
+		tree.Insert(s)
+	}
+	tests := [...]struct {
+		prefix string
+		n      int
+	}{
+		{"", 7},
+		{"r", 7},
+		{"rom", 3},
+		{"roma", 2},
+		{"romanus", 1},
+		{"rub", 4},
+		{"rubic", 2},
+		{"rubicundusx", 0},
+		{"x", 0},
+	}
+	for _, test := range tests {
+		var got []string
+		tree.WithPrefix(test.prefix, func(s string) {
+			got = append(got, s)
+		})
+		if len(got) != test.n {
+			t.Errorf("WithPrefix(%s) visited %v, expected %d strings", test.prefix, got, test.n)
+		}
+		for i, s := range got {
+			if !strings.HasPrefix(s, test.prefix) || !tree.Member(s) {
+				t.Errorf("WithPrefix(%s) visited %s", test.prefix, s)
+			}
+			if i > 0 && got[i-1] >= s {
+				t.Errorf("WithPrefix(%s): [%s] came before [%s]", test.prefix, got[i-1], s)
+			}
+		}
+	}
+}
+
+func TestLongestPrefixOf(t *testing.T) {
+	var tree T
+	for _, s := range []string{"/", "/api", "/api/v1", "/api/v1/users", "/static"} {
+		tree.Insert(s)
+	}
+	tests := [...]struct {
+		s, longest string
+		ok         bool
+	}{
+		{"/api/v1/users/42", "/api/v1/users", true},
+		{"/api/v1/user", "/api/v1", true},
+		{"/api/v2", "/api", true},
+		{"/apiary", "/api", true},
+		{"/static", "/static", true},
+		{"/", "/", true},
+		{"", "", false},
+		{"api", "", false},
+	}
+	for _, test := range tests {
+		longest, ok := tree.LongestPrefixOf(test.s)
+		if longest != test.longest || ok != test.ok {
+			t.Errorf("LongestPrefixOf(%s)=%s, %t, expected %s, %t",
+				test.s, longest, ok, test.longest, test.ok)
+		}
+	}
+
+	tree.Insert("")
+	if longest, ok := tree.LongestPrefixOf("api"); longest != "" || !ok {
+		t.Errorf("LongestPrefixOf(api)=%s, %t, expected the empty string", longest, ok)
+	}
+}
+
+func TestMapPutGet(t *testing.T) {
+	var m Map[int]
+	for i, s := range romans {
+		m.Put(s, i)
+	}
+	m.Put("", -1)
+	checkCompact(t, &m, true)
+	for i, s := range romans {
+		if v, ok := m.Get(s); !ok || v != i {
+			t.Errorf("Get(%s)=%d, %t, expected %d, true", s, v, ok, i)
+		}
+	}
+	for _, s := range []string{"r", "rom", "rubico", "abc"} {
+		if v, ok := m.Get(s); ok || v != 0 {
+			t.Errorf("Get(%s)=%d, %t, expected 0, false", s, v, ok)
+		}
+	}
+	if v, ok := m.Get(""); !ok || v != -1 {
+		t.Errorf("Get()=%d, %t, expected -1, true", v, ok)
+	}
+
+	m.Put("ruber", 100)
+	if v, _ := m.Get("ruber"); v != 100 {
+		t.Errorf("Put did not replace the value of ruber, got %d", v)
+	}
+	if m.Len() != len(romans)+1 {
+		t.Errorf("Len=%d, expected %d", m.Len(), len(romans)+1)
+	}
+}
+
+// TestMapDelete checks that values move with their nodes
+// when nodes are split by Put and merged by Delete.
+func TestMapDelete(t *testing.T) {
+	var m Map[string]
+	for _, s := range []string{"abcdf", "abc", "abcde", "ab"} {
+		m.Put(s, s)
+	}
+	for _, s := range []string{"abc", "ab", "abcde"} {
+		if !m.Delete(s) {
+			t.Errorf("failed to delete %s", s)
+		}
+		checkCompact(t, &m, true)
+		if v, ok := m.Get("abcdf"); !ok || v != "abcdf" {
+			t.Errorf("after deleting %s, Get(abcdf)=%s, %t", s, v, ok)
+		}
+		if v, ok := m.Get(s); ok || v != "" {
+			t.Errorf("after deleting %s, Get(%s)=%s, %t", s, s, v, ok)
+		}
+	}
+	if m.prefix != "abcdf" || m.val != "abcdf" || len(m.kids) != 0 {
+		t.Errorf("expected a root of abcdf, got [%s]=%s", m.prefix, m.val)
+	}
+}
+
+func TestMapIterate(t *testing.T) {
+	var m Map[int]
+	for _, s := range romans {
+		m.Put(s, len(s))
+	}
+	n := 0
+	last := ""
+	m.Iterate(func(s string, v int) {
+		if last >= s {
+			t.Errorf("[%s] came before [%s]", last, s)
+		}
+		if v != len(s) {
+			t.Errorf("value of %s is %d, expected %d", s, v, len(s))
+		}
+		last = s
+		n++
+	})
+	if n != len(romans) {
+		t.Errorf("Only visited %d strings, expected %d", n, len(romans))
+	}
+
+	var got []string
+	m.WithPrefix("rub", func(s string, v int) {
+		if v != len(s) {
+			t.Errorf("value of %s is %d, expected %d", s, v, len(s))
+		}
+		got = append(got, s)
+	})
+	if strings.Join(got, " ") != "rubens ruber rubicon rubicundus" {
+		t.Errorf("WithPrefix(rub) visited %v", got)
+	}
+}
+
+func TestMapLongestPrefixOf(t *testing.T) {
+	var routes Map[string]
+	routes.Put("/", "root")
+	routes.Put("/api", "api")
+	routes.Put("/api/v1/users", "users")
+	tests := [...]struct{ path, prefix, route string }{
+		{"/api/v1/users/42", "/api/v1/users", "users"},
+		{"/api/v1", "/api", "api"},
+		{"/index.html", "/", "root"},
+		{"", "", ""},
+	}
+	for _, test := range tests {
+		prefix, route, ok := routes.LongestPrefixOf(test.path)
+		if prefix != test.prefix || route != test.route || ok != (test.route != "") {
+			t.Errorf("LongestPrefixOf(%s)=%s, %s, %t, expected %s, %s",
+				test.path, prefix, route, ok, test.prefix, test.route)
+		}
+	}
+}
+
+// levenshtein returns the edit distance between two strings.
+func levenshtein(a, b string) int {
+	row := make([]int, len(b)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		prev := row[0]
+		row[0] = i
+		for j := 1; j <= len(b); j++ {
+			sub := prev
+			if a[i-1] != b[j-1] {
+				sub++
+			}
+			prev = row[j]
+			row[j] = min(sub, min(row[j], row[j-1])+1)
+		}
+	}
+	return row[len(b)]
+}
+
+func TestFuzzy(t *testing.T) {
+	words := append(romans[:], "", "a", "rub", "rubber", "robe", "roman", "xyz")
+	var tree T
+	for _, w := range words {
+		tree.Insert(w)
+	}
+	queries := [...]string{"", "ruben", "romans", "rubicund", "xy", "q", "rubiconnn"}
+	for _, q := range queries {
+		for max := 0; max <= 3; max++ {
+			want := make(map[string]int)
+			for _, w := range words {
+				if d := levenshtein(q, w); d <= max {
+					want[w] = d
+				}
+			}
+			n := 0
+			last := ""
+			tree.Fuzzy(q, max, func(s string, d int) {
+				if n > 0 && last >= s {
+					t.Errorf("Fuzzy(%s, %d): [%s] came before [%s]", q, max, last, s)
+				}
+				if wd, ok := want[s]; !ok || wd != d {
+					t.Errorf("Fuzzy(%s, %d) visited %s at distance %d, expected %d",
+						q, max, s, d, levenshtein(q, s))
+				}
+				last = s
+				n++
+			})
+			if n != len(want) {
+				t.Errorf("Fuzzy(%s, %d) visited %d strings, expected %v", q, max, n, want)
+			}
+		}
+	}
+}
+
+func TestMapFuzzy(t *testing.T) {
+	var m Map[int]
+	for i, s := range romans {
+		m.Put(s, i)
+	}
+	var got []string
+	m.Fuzzy("ruben", 1, func(s string, v, d int) {
+		if romans[v] != s {
+			t.Errorf("value of %s is %d", s, v)
+		}
+		got = append(got, s)
+	})
+	if strings.Join(got, " ") != "rubens ruber" {
+		t.Errorf("Fuzzy(ruben, 1) visited %v", got)
+	}
+}
+
+// firstUnit returns the first byte of s or, if runes is true,
+// the bytes of its first rune.
+func firstUnit(s string, runes bool) string {
+	if !runes {
+		return s[:1]
+	}
+	_, n := utf8.DecodeRuneInString(s)
+	return s[:n]
+}
+
+var multilingual = [...]string{
+	"",
+	"a",
+	"cafe",
+	"café",
+	"cafés",
+	"naive",
+	"naïve",
+	"é",
+	"è",
+	"ée",
+	"日本",
+	"日本語",
+	"日曜日",
+	"中文",
+	"中国",
+	"Ελλάδα",
+	"Ελληνικά",
+	"русский",
+	"руль",
+	"🙂",
+	"🙃",
+}
+
+// checkRunePrefixes fails the test if the prefix of any node
+// is not valid UTF-8, that is, if an edge splits a rune.
+func checkRunePrefixes[V any](t *testing.T, tree *Map[V]) {
+	if !utf8.ValidString(tree.prefix) {
+		t.Errorf("prefix %q is not valid UTF-8", tree.prefix)
+	}
+	for i := range tree.kids {
+		checkRunePrefixes(t, &tree.kids[i])
+	}
+}
+
+func TestMultilingual(t *testing.T) {
+	sorted := append([]string{}, multilingual[:]...)
+	sort.Strings(sorted)
+
+	for _, runes := range []bool{false, true} {
+		var tree T
+		if runes {
+			tree.SplitRunes()
+		}
+		for _, s := range multilingual {
+			tree.Insert(s)
+		}
+		checkCompact(t, tree.m(), true)
+		if runes {
+			checkRunePrefixes(t, tree.m())
+		}
+
+		var got []string
+		tree.Iterate(func(s string) { got = append(got, s) })
+		if strings.Join(got, " ") != strings.Join(sorted, " ") {
+			t.Errorf("runes=%t: Iterate visited %q, expected %q", runes, got, sorted)
+		}
+		for _, s := range multilingual {
+			if !tree.Member(s) || !tree.MemberBytes([]byte(s)) {
+				t.Errorf("runes=%t: %s is not a member", runes, s)
+			}
+		}
+		for _, s := range []string{"caf", "日", "\xe6\x97", "Ελλ", "🙂🙂", "\xc3"} {
+			if tree.Member(s) || tree.MemberBytes([]byte(s)) {
+				t.Errorf("runes=%t: %q is a member", runes, s)
+			}
+		}
+
+		// Prefixes that end within a rune select the same
+		// strings in either mode.
+		for _, p := range []string{"日", "日本", "\xe6", "\xe6\x97", "caf", "café", "\xc3", "Ελλ", "\xf0\x9f\x99"} {
+			var got, want []string
+			tree.WithPrefix(p, func(s string) { got = append(got, s) })
+			for _, s := range sorted {
+				if strings.HasPrefix(s, p) {
+					want = append(want, s)
+				}
+			}
+			if strings.Join(got, " ") != strings.Join(want, " ") {
+				t.Errorf("runes=%t: WithPrefix(%q) visited %q, expected %q", runes, p, got, want)
+			}
+		}
+
+		for _, s := range multilingual[:len(multilingual)/2] {
+			tree.Delete(s)
+			checkCompact(t, tree.m(), true)
+			if runes {
+				checkRunePrefixes(t, tree.m())
+			}
+		}
+		if tree.Len() != len(multilingual)-len(multilingual)/2 {
+			t.Errorf("runes=%t: Len=%d after deleting", runes, tree.Len())
+		}
+	}
+}
+
+// TestSplitRunes checks that a byte-mode tree splits "é" and "è"
+// after their common first byte, but a rune-mode tree does not.
+func TestSplitRunes(t *testing.T) {
+	var bytes, runes T
+	runes.SplitRunes()
+	for _, s := range []string{"é", "è"} {
+		bytes.Insert(s)
+		runes.Insert(s)
+	}
+	if bytes.prefix != "\xc3" || len(bytes.kids) != 2 {
+		t.Errorf("expected a byte-mode root of \\xc3, got %q", bytes.prefix)
+	}
+	if runes.prefix != "" || len(runes.kids) != 2 || runes.kids[0].prefix != "è" {
+		t.Errorf("expected a rune-mode root of \"\", got %q", runes.prefix)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("SplitRunes on a non-empty tree did not panic")
+		}
+	}()
+	bytes.SplitRunes()
+}
+
+func TestInsertBytes(t *testing.T) {
+	var tree T
+	for _, s := range multilingual {
+		tree.InsertBytes([]byte(s))
+	}
+	for _, s := range multilingual {
+		if !tree.Member(s) {
+			t.Errorf("%s is not a member", s)
+		}
+	}
+	if tree.Len() != len(multilingual) {
+		t.Errorf("Len=%d, expected %d", tree.Len(), len(multilingual))
+	}
+
+	// The tree must not share memory with the byte slices.
+	b := []byte("zzz")
+	tree.InsertBytes(b)
+	b[0] = 'y'
+	if !tree.Member("zzz") {
+		t.Errorf("InsertBytes kept a reference to its argument")
+	}
+
+	b = []byte("日本語")
+	if n := testing.AllocsPerRun(100, func() { tree.MemberBytes(b) }); n != 0 {
+		t.Errorf("MemberBytes made %g allocations", n)
+	}
+	if n := testing.AllocsPerRun(100, func() { tree.InsertBytes(b) }); n != 0 {
+		t.Errorf("InsertBytes of a member made %g allocations", n)
+	}
+}
+
+func TestFuzzyRunes(t *testing.T) {
+	for _, test := range []struct {
+		runes bool
+		d     int
+	}{{false, 2}, {true, 1}} {
+		var tree T
+		if test.runes {
+			tree.SplitRunes()
+		}
+		for _, s := range multilingual {
+			tree.Insert(s)
+		}
+		got := -1
+		tree.Fuzzy("cafe", 2, func(s string, d int) {
+			if s == "café" {
+				got = d
+			}
+		})
+		if got != test.d {
+			t.Errorf("runes=%t: distance from cafe to café is %d, expected %d", test.runes, got, test.d)
+		}
+	}
+}
+
+// checkFrozen fails the test if the Frozen set does not
+// have the same strings and prefixes as the tree.
+func checkFrozen(t *testing.T, tree *T, f *Frozen, probes []string) {
+	if f.Len() != tree.Len() {
+		t.Errorf("Len=%d, expected %d", f.Len(), tree.Len())
+	}
+	var got, want []string
+	f.Iterate(func(s string) { got = append(got, s) })
+	tree.Iterate(func(s string) { want = append(want, s) })
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Iterate visited %q, expected %q", got, want)
+	}
+	for _, s := range probes {
+		if f.Member(s) != tree.Member(s) || f.MemberBytes([]byte(s)) != tree.Member(s) {
+			t.Errorf("Member(%q)=%t, expected %t", s, f.Member(s), tree.Member(s))
+		}
+		for _, p := range []string{s, s[:len(s)/2], s[:len(s)/3]} {
+			got, want = nil, nil
+			f.WithPrefix(p, func(s string) { got = append(got, s) })
+			tree.WithPrefix(p, func(s string) { want = append(want, s) })
+			if strings.Join(got, " ") != strings.Join(want, " ") {
+				t.Errorf("WithPrefix(%q) visited %q, expected %q", p, got, want)
+			}
+		}
+	}
+}
+
+func TestFreeze(t *testing.T) {
+	probes := append(romans[:], multilingual[:]...)
+	probes = append(probes, "caf", "日", "\xe6\x97", "romulu", "rubicundusx", "\xc3", "zzz")
+
+	var empty T
+	f, err := Open(mustFreeze(t, &empty))
+	if err != nil {
+		t.Fatalf("Open of an empty set failed: %s", err)
+	}
+	checkFrozen(t, &empty, f, probes)
+
+	for _, runes := range []bool{false, true} {
+		var tree T
+		if runes {
+			tree.SplitRunes()
+		}
+		for _, s := range probes[:len(romans)+len(multilingual)] {
+			tree.Insert(s)
+		}
+		f, err := Open(mustFreeze(t, &tree))
+		if err != nil {
+			t.Fatalf("runes=%t: Open failed: %s", runes, err)
+		}
+		if f.runes != runes {
+			t.Errorf("runes=%t: Open returned a set with runes=%t", runes, f.runes)
+		}
+		checkFrozen(t, &tree, f, probes)
+
+		b := []byte("rubicundus")
+		if n := testing.AllocsPerRun(100, func() { f.MemberBytes(b) }); n != 0 {
+			t.Errorf("runes=%t: MemberBytes made %g allocations", runes, n)
+		}
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	var tree T
+	for _, s := range romans {
+		tree.Insert(s)
+	}
+	data := mustFreeze(t, &tree)
+
+	for n := 0; n < len(data); n++ {
+		if _, err := Open(data[:n]); err != ErrFormat {
+			t.Errorf("Open of %d of %d bytes returned %v, expected ErrFormat", n, len(data), err)
+		}
+	}
+	if _, err := Open(append(data[:len(data):len(data)], 0)); err != ErrFormat {
+		t.Errorf("Open with a trailing byte returned %v, expected ErrFormat", err)
+	}
+
+	// Open must never panic, and a set that it returns must be
+	// usable, whatever byte is corrupted.
+	bad := make([]byte, len(data))
+	for i := range data {
+		for _, x := range []byte{0, 1, 0x7f, 0x80, 0xff} {
+			copy(bad, data)
+			bad[i] ^= x
+			f, err := Open(bad)
+			if err != nil {
+				continue
+			}
+			n := 0
+			f.Iterate(func(string) { n++ })
+			if n != f.Len() {
+				t.Errorf("byte %d^%#x: Iterate visited %d strings, Len=%d", i, x, n, f.Len())
+			}
+			for _, s := range romans {
+				f.Member(s)
+				f.WithPrefix(s[:2], func(string) {})
+			}
+		}
+	}
+}
+
+// TestFreezeTooLarge tests that Freeze reports an error, rather than
+// writing wrong offsets, when the layout exceeds its size limit.
+func TestFreezeTooLarge(t *testing.T) {
+	var tree T
+	for _, s := range romans {
+		tree.Insert(s)
+	}
+	data := mustFreeze(t, &tree)
+
+	for max := uint64(0); max < uint64(len(data)); max++ {
+		if _, err := tree.freeze(max); err != ErrTooLarge {
+			t.Errorf("freeze(%d) of %d bytes returned %v, expected ErrTooLarge", max, len(data), err)
+		}
+	}
+	got, err := tree.freeze(uint64(len(data)))
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("freeze(%d) of %d bytes returned %v", len(data), len(data), err)
+	}
+}
+
+func mustFreeze(t *testing.T, tree *T) []byte {
+	data, err := tree.Freeze()
+	if err != nil {
+		t.Fatalf("Freeze failed: %s", err)
+	}
+	return data
+}
+
+// TestOpenDeep tests that Open accepts a layout of the maximum
+// depth, and rejects a deeper one, without recursing per level.
+func TestOpenDeep(t *testing.T) {
+	for _, depth := range []int{maxFrozenDepth, maxFrozenDepth + 1} {
+		// A chain of nodes, each with the prefix "a" and one kid,
+		// and the last a member with no kids.
+		data := append([]byte(frozenMagic), frozenVersion, 0, 1, 0, 0, 0)
+		data = append(data, 0, 0, 1)
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(data)+4))
+		for i := 1; i < depth-1; i++ {
+			data = append(data, 0, 1, 1, 'a')
+			data = binary.LittleEndian.AppendUint32(data, uint32(len(data)+4))
+		}
+		data = append(data, flagMember, 1, 0, 'a')
+
+		f, err := Open(data)
+		switch {
+		case depth > maxFrozenDepth && err != ErrFormat:
+			t.Errorf("Open of depth %d returned %v, expected ErrFormat", depth, err)
+		case depth <= maxFrozenDepth && err != nil:
+			t.Errorf("Open of depth %d returned %v", depth, err)
+		case depth <= maxFrozenDepth && !f.Member(strings.Repeat("a", depth-1)):
+			t.Errorf("Open of depth %d is missing its string", depth)
+		}
+	}
+}